@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestAuthAndRoles(t *testing.T) {
+	s := newTestServer(t)
+	admin, _ := s.signup("boss")
+	player, playerID := s.signup("alice")
+	moderator, moderatorID := s.signup("bob")
+	banned, bannedID := s.signup("carol")
+
+	if rec := s.do("PUT", "/api/admin/users/"+moderatorID+"/role", admin, map[string]string{"role": model.RoleModerator}); rec.Code != http.StatusOK {
+		t.Fatalf("attribution du rôle : %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := s.do("POST", "/api/admin/users/"+bannedID+"/ban", moderator, nil); rec.Code != http.StatusOK {
+		t.Fatalf("bannissement : %d %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"sans token", "GET", "/api/gacha/banners", "", http.StatusUnauthorized},
+		{"token invalide", "GET", "/api/gacha/banners", "inconnu", http.StatusUnauthorized},
+		{"joueur authentifié", "GET", "/api/gacha/banners", player, http.StatusOK},
+		// le bannissement ferme les sessions du joueur
+		{"joueur banni", "GET", "/api/gacha/banners", banned, http.StatusUnauthorized},
+		{"transactions d'un autre joueur", "GET", "/api/user/transactions/" + moderatorID, player, http.StatusForbidden},
+		{"ses propres transactions", "GET", "/api/user/transactions/" + playerID, player, http.StatusOK},
+		{"joueur sur une route de modération", "GET", "/api/admin/users", player, http.StatusForbidden},
+		{"modérateur sur une route de modération", "GET", "/api/admin/users", moderator, http.StatusOK},
+		{"modérateur sur une route d'admin", "GET", "/api/admin/ledger/check", moderator, http.StatusForbidden},
+		{"admin sur une route d'admin", "GET", "/api/admin/ledger/check", admin, http.StatusOK},
+		{"joueur qui débannit un joueur", "POST", "/api/admin/users/" + bannedID + "/unban", player, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.do(tt.method, tt.path, tt.token, nil); rec.Code != tt.want {
+				t.Errorf("%s %s : %d, attendu %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	// le mot de passe n'est jamais renvoyé
+	rec := s.do("GET", "/api/admin/users", admin, nil)
+	if bytes.Contains(rec.Body.Bytes(), []byte("Password")) || bytes.Contains(rec.Body.Bytes(), []byte("$2a$")) {
+		t.Errorf("mot de passe présent dans la liste des utilisateurs : %s", rec.Body.String())
+	}
+}
+
+func TestAdminBalanceAdjustments(t *testing.T) {
+	s := newTestServer(t)
+	t.Setenv("ADMIN_USERNAMES", "boss,chief")
+	admin, adminID := s.signup("boss")
+	_, otherAdminID := s.signup("chief")
+	_, playerID := s.signup("alice")
+	coins := map[string]interface{}{"delta": 100, "reason": "test"}
+	items := map[string]interface{}{"rarity": 3, "delta": 1, "reason": "test"}
+	if role := s.user(otherAdminID).Role; role != model.RoleAdmin {
+		t.Fatalf("rôle de chief : %s, attendu %s", role, model.RoleAdmin)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		body map[string]interface{}
+		want int
+	}{
+		{"pièces sur son propre compte", "/api/admin/users/" + adminID + "/coins", coins, http.StatusForbidden},
+		{"antisèches sur son propre compte", "/api/admin/users/" + adminID + "/inventory", items, http.StatusForbidden},
+		{"pièces d'un utilisateur inconnu", "/api/admin/users/inconnu/coins", coins, http.StatusNotFound},
+		{"pièces d'un joueur", "/api/admin/users/" + playerID + "/coins", coins, http.StatusOK},
+		{"antisèches d'un joueur", "/api/admin/users/" + playerID + "/inventory", items, http.StatusOK},
+		{"pièces d'un autre admin", "/api/admin/users/" + otherAdminID + "/coins", coins, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.do("POST", tt.path, admin, tt.body); rec.Code != tt.want {
+				t.Errorf("POST %s : %d, attendu %d (%s)", tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	if self := s.user(adminID); self.LedgerSeq != 0 {
+		t.Errorf("le compte de l'admin a été modifié : %+v", self)
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestVerifyAnswerIdempotent(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.signup("alice")
+	quizID := s.startQuiz(token, "alice")
+	answer := map[string]interface{}{"quizID": quizID, "answer": "A"}
+
+	var first, replay struct {
+		Correct      bool `json:"correct"`
+		Mark         int  `json:"mark"`
+		NextQuestion int  `json:"nextQuestion"`
+	}
+	rec := s.do("POST", "/api/quiz/verifyAnswer", token, answer, "Idempotency-Key", "reponse-1")
+	if decode(t, rec, &first); rec.Code != http.StatusOK || !first.Correct || first.NextQuestion != 1 {
+		t.Fatalf("première réponse : %d %s", rec.Code, rec.Body.String())
+	}
+
+	// la même requête renvoyée par le client retourne le même résultat sans compter deux fois
+	rec = s.do("POST", "/api/quiz/verifyAnswer", token, answer, "Idempotency-Key", "reponse-1")
+	if decode(t, rec, &replay); rec.Code != http.StatusOK || replay != first {
+		t.Errorf("réponse rejouée : %d %s, attendu %+v", rec.Code, rec.Body.String(), first)
+	}
+	quiz, err := s.store.GetQuizByID(context.Background(), quizID)
+	if err != nil || quiz.Number_question != 1 || quiz.Mark != 1 {
+		t.Errorf("quiz après la réponse rejouée : question %d, note %d (%v)", quiz.Number_question, quiz.Mark, err)
+	}
+
+	// une réponse à une question déjà passée est refusée
+	stale := map[string]interface{}{"quizID": quizID, "answer": "A", "question": 0}
+	if rec = s.do("POST", "/api/quiz/verifyAnswer", token, stale); rec.Code != http.StatusConflict {
+		t.Errorf("réponse à la question 0 : %d, attendu %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestUseCheatSheet(t *testing.T) {
+	s := newTestServer(t)
+	token, userID := s.signup("alice")
+	quizID := s.startQuiz(token, "alice")
+	quantity := func(rarity int) int {
+		for _, item := range s.user(userID).Inventory {
+			if item.Rarity == rarity {
+				return item.Quantity
+			}
+		}
+		return 0
+	}
+	use := func(rarity int) (int, []string) {
+		var hints []string
+		rec := s.do("POST", "/api/cheatsheet", token, map[string]interface{}{"quizID": quizID, "rarity": rarity})
+		if rec.Code == http.StatusOK {
+			decode(t, rec, &hints)
+		}
+		return rec.Code, hints
+	}
+
+	code, hints := use(3)
+	if code != http.StatusOK || len(hints) != 1 || hints[0] == "A" {
+		t.Fatalf("antisèche de rareté 3 : %d, indices %v", code, hints)
+	}
+	if quantity(3) != 0 {
+		t.Errorf("%d antisèche(s) de rareté 3 après utilisation, attendu 0", quantity(3))
+	}
+
+	// une seule antisèche par question, la seconde n'est pas débitée
+	if code, _ = use(4); code != http.StatusConflict {
+		t.Errorf("seconde antisèche sur la même question : %d, attendu %d", code, http.StatusConflict)
+	}
+	if quantity(4) != 1 {
+		t.Errorf("antisèche de rareté 4 débitée par une utilisation refusée")
+	}
+
+	s.do("POST", "/api/quiz/verifyAnswer", token, map[string]interface{}{"quizID": quizID, "answer": "A"})
+	if code, hints = use(4); code != http.StatusOK || len(hints) != 2 {
+		t.Errorf("antisèche sur la question suivante : %d, indices %v", code, hints)
+	}
+	s.do("POST", "/api/quiz/verifyAnswer", token, map[string]interface{}{"quizID": quizID, "answer": "A"})
+
+	// sans antisèche de cette rareté, la question n'est pas marquée
+	if code, _ = use(3); code != http.StatusConflict {
+		t.Errorf("antisèche épuisée : %d, attendu %d", code, http.StatusConflict)
+	}
+	quiz, _ := s.store.GetQuizByID(context.Background(), quizID)
+	if quiz.CheatSheetsUsed != 2 || quiz.Answers[0].CheatSheet != 3 || quiz.Answers[1].CheatSheet != 4 || quiz.Answers[2].CheatSheet != 0 {
+		t.Errorf("antisèches notées sur le quiz : %d, %+v", quiz.CheatSheetsUsed, quiz.Answers[:3])
+	}
+	if used := s.user(userID).Stats.UsedCheatSheets; used != 2 {
+		t.Errorf("%d antisèches dans les statistiques, attendu 2", used)
+	}
+	var entries []model.LedgerEntry
+	decode(t, s.do("GET", "/api/user/transactions/"+userID, token, nil), &entries)
+	if len(entries) != 2 || entries[0].Kind != model.LedgerCheatSheet || entries[0].Reference != quizID {
+		t.Errorf("journal : %+v", entries)
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGachaBanners(t *testing.T) {
+	s := newTestServer(t)
+	admin, _ := s.signup("boss")
+	token, userID := s.signup("alice")
+	banner := map[string]interface{}{
+		"name":    "Bannière de Noël",
+		"weights": []map[string]interface{}{{"rarity": 6, "weight": 1}},
+		"pulls":   []map[string]interface{}{{"quantity": 1, "price": 50}},
+	}
+	type bannerView struct {
+		ID string `json:"id"`
+	}
+	listed := func(path, token string) map[string]bool {
+		var views []bannerView
+		decode(t, s.do("GET", path, token, nil), &views)
+		ids := make(map[string]bool)
+		for _, view := range views {
+			ids[view.ID] = true
+		}
+		return ids
+	}
+	rarity6 := func() int {
+		for _, item := range s.user(userID).Inventory {
+			if item.Rarity == 6 {
+				return item.Quantity
+			}
+		}
+		return 0
+	}
+
+	if rec := s.do("PUT", "/api/admin/banners/noel", token, banner); rec.Code != http.StatusForbidden {
+		t.Errorf("bannière enregistrée par un joueur : %d", rec.Code)
+	}
+	invalid := map[string]interface{}{"name": "Vide", "pulls": banner["pulls"]}
+	if rec := s.do("PUT", "/api/admin/banners/vide", admin, invalid); rec.Code != http.StatusBadRequest {
+		t.Errorf("bannière sans rareté : %d, attendu %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := s.do("PUT", "/api/admin/banners/noel", admin, banner); rec.Code != http.StatusOK {
+		t.Fatalf("enregistrement de la bannière : %d %s", rec.Code, rec.Body.String())
+	}
+	if ids := listed("/api/gacha/banners", token); !ids["noel"] || !ids["standard"] || ids["vide"] {
+		t.Errorf("bannières ouvertes : %v", ids)
+	}
+
+	// le tirage suit la table de la bannière et son prix
+	coins, before := s.user(userID).Coins, rarity6()
+	pull := map[string]interface{}{"username": "alice", "bannerID": "noel", "quantity": 1}
+	if rec := s.do("POST", "/api/gacha/pull", token, pull); rec.Code != http.StatusOK {
+		t.Fatalf("tirage sur la bannière : %d %s", rec.Code, rec.Body.String())
+	}
+	if after := s.user(userID); after.Coins != coins-50 || rarity6() != before+1 {
+		t.Errorf("%d pièces et %d antisèches de rareté 6 après le tirage, attendu %d et %d", after.Coins, rarity6(), coins-50, before+1)
+	}
+	if rec := s.do("POST", "/api/gacha/pull", token, map[string]interface{}{"username": "alice", "bannerID": "noel", "quantity": 10}); rec.Code != http.StatusBadRequest {
+		t.Errorf("tirage non proposé par la bannière : %d, attendu %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// une bannière fermée n'est plus proposée aux joueurs
+	banner["endsAt"] = time.Now().Add(-time.Minute)
+	if rec := s.do("PUT", "/api/admin/banners/noel", admin, banner); rec.Code != http.StatusOK {
+		t.Fatalf("fermeture de la bannière : %d %s", rec.Code, rec.Body.String())
+	}
+	if ids := listed("/api/gacha/banners", token); ids["noel"] {
+		t.Errorf("bannière fermée toujours proposée : %v", ids)
+	}
+	if ids := listed("/api/admin/banners", admin); !ids["noel"] {
+		t.Errorf("bannière fermée absente de la liste d'administration : %v", ids)
+	}
+	coins = s.user(userID).Coins
+	if rec := s.do("POST", "/api/gacha/pull", token, pull); rec.Code != http.StatusNotFound {
+		t.Errorf("tirage sur une bannière fermée : %d, attendu %d", rec.Code, http.StatusNotFound)
+	}
+	if after := s.user(userID); after.Coins != coins {
+		t.Errorf("%d pièces après un tirage refusé, attendu %d", after.Coins, coins)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestGachaPullAtomic(t *testing.T) {
+	s := newTestServer(t)
+	token, userID := s.signup("alice")
+	start := s.user(userID)
+	count := func(user model.User) int {
+		total := 0
+		for _, item := range user.Inventory {
+			total += item.Quantity
+		}
+		return total
+	}
+
+	tests := []struct {
+		name      string
+		body      map[string]interface{}
+		want      int
+		wantCoins int
+		wantItems int
+	}{
+		{"bannière inconnue", map[string]interface{}{"bannerID": "inconnue", "quantity": 1}, http.StatusNotFound, start.Coins, count(start)},
+		{"nombre de tirages non proposé", map[string]interface{}{"quantity": 3}, http.StatusBadRequest, start.Coins, count(start)},
+		{"dix tirages", map[string]interface{}{"quantity": 10}, http.StatusOK, start.Coins - 900, count(start) + 10},
+		{"solde insuffisant", map[string]interface{}{"quantity": 10}, http.StatusConflict, start.Coins - 900, count(start) + 10},
+		{"un tirage", map[string]interface{}{"quantity": 1}, http.StatusOK, start.Coins - 1000, count(start) + 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := s.user(userID)
+			tt.body["username"] = "alice"
+			if rec := s.do("POST", "/api/gacha/pull", token, tt.body); rec.Code != tt.want {
+				t.Fatalf("tirage : %d, attendu %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+			after := s.user(userID)
+			if after.Coins != tt.wantCoins || count(after) != tt.wantItems {
+				t.Errorf("%d pièces et %d antisèches, attendu %d et %d", after.Coins, count(after), tt.wantCoins, tt.wantItems)
+			}
+			if tt.want != http.StatusOK && (after.LedgerSeq != before.LedgerSeq || len(after.Inventory) != len(before.Inventory)) {
+				t.Errorf("utilisateur modifié par un tirage refusé : %+v", after)
+			}
+		})
+	}
+}
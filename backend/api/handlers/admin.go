@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"quizmaster/db"
+	"quizmaster/model"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// enregistre une action d'administration dans le journal d'audit
+func recordAudit(r *http.Request, action string, target string, details map[string]interface{}) {
+	actor, _ := CurrentUser(r)
+	entry := model.AuditEntry{
+		Actor:     actor.Username,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+	if err := store.InsertAuditEntry(r.Context(), entry); err != nil {
+		log.Printf("Erreur lors de l'écriture du journal d'audit (%s sur %s) : %v", action, target, err)
+	}
+}
+
+// récupère l'utilisateur {userid} ciblé par une action de modération.
+// Un modérateur ne peut pas agir sur un utilisateur de rang égal ou supérieur, ni sur lui-même.
+func moderationTarget(w http.ResponseWriter, r *http.Request) (model.User, bool) {
+	actor, _ := CurrentUser(r)
+	target, err := store.GetUserByID(r.Context(), mux.Vars(r)["userid"])
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, "Utilisateur introuvable", nil)
+		return target, false
+	}
+	if target.ID == actor.ID {
+		writeJSON(w, http.StatusForbidden, "Action impossible sur son propre compte", nil)
+		return target, false
+	}
+	if actor.Role != model.RoleAdmin && model.RoleRank(target.Role) >= model.RoleRank(actor.Role) {
+		writeJSON(w, http.StatusForbidden, "Privilèges insuffisants pour agir sur cet utilisateur", nil)
+		return target, false
+	}
+	return target, true
+}
+
+// ================== Utilisateurs ==================
+
+// AdminListUsersHandler liste tous les utilisateurs
+func AdminListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	users, err := store.GetAllUsers(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération des utilisateurs", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Utilisateurs récupérés avec succès", users)
+}
+
+// AdminBanUserHandler bannit un utilisateur et ferme toutes ses sessions
+func AdminBanUserHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(r.Body).Decode(&requestData) // la raison est facultative
+
+	target, ok := moderationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.SetUserBanned(r.Context(), target.ID, true); err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors du bannissement", nil)
+		return
+	}
+	if _, err := store.DeleteUserSessions(r.Context(), target.ID); err != nil {
+		log.Printf("Erreur lors de la révocation des sessions de %s : %v", target.Username, err)
+	}
+
+	recordAudit(r, "user.ban", target.Username, map[string]interface{}{"reason": requestData.Reason})
+	writeJSON(w, http.StatusOK, "Utilisateur banni", nil)
+}
+
+// AdminUnbanUserHandler lève le bannissement d'un utilisateur
+func AdminUnbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	target, ok := moderationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.SetUserBanned(r.Context(), target.ID, false); err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors du débannissement", nil)
+		return
+	}
+
+	recordAudit(r, "user.unban", target.Username, nil)
+	writeJSON(w, http.StatusOK, "Utilisateur débanni", nil)
+}
+
+// AdminSetRoleHandler change le rôle d'un utilisateur
+func AdminSetRoleHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || !model.ValidRole(requestData.Role) {
+		writeJSON(w, http.StatusBadRequest, "Rôle invalide (player, creator, moderator, admin)", nil)
+		return
+	}
+
+	target, ok := moderationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := store.SetUserRole(r.Context(), target.ID, requestData.Role); err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors du changement de rôle", nil)
+		return
+	}
+
+	recordAudit(r, "user.role", target.Username, map[string]interface{}{"from": target.Role, "to": requestData.Role})
+	writeJSON(w, http.StatusOK, "Rôle mis à jour", nil)
+}
+
+// AdminAdjustCoinsHandler ajoute ou retire des pièces à un utilisateur
+func AdminAdjustCoinsHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Delta  int    `json:"delta"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Delta == 0 {
+		writeJSON(w, http.StatusBadRequest, "Montant invalide", nil)
+		return
+	}
+
+	target, ok := moderationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := store.AdjustCoins(r.Context(), target.ID, requestData.Delta, requestData.Reason)
+	if err == db.ErrNotFound {
+		writeJSON(w, http.StatusNotFound, "Utilisateur introuvable", nil)
+		return
+	}
+	if err == db.ErrInsufficientBalance {
+		writeJSON(w, http.StatusConflict, "Le solde ne peut pas devenir négatif", nil)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la mise à jour des pièces", nil)
+		return
+	}
+
+	recordAudit(r, "user.coins", user.Username, map[string]interface{}{"delta": requestData.Delta, "balance": user.Coins, "reason": requestData.Reason})
+	writeJSON(w, http.StatusOK, "Pièces mises à jour", map[string]int{"coins": user.Coins})
+}
+
+// AdminAdjustInventoryHandler ajoute ou retire des antisèches d'une rareté à un utilisateur
+func AdminAdjustInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	var requestData struct {
+		Rarity int    `json:"rarity"`
+		Delta  int    `json:"delta"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Delta == 0 || requestData.Rarity < 3 || requestData.Rarity > 6 {
+		writeJSON(w, http.StatusBadRequest, "Rareté (3 à 6) ou quantité invalide", nil)
+		return
+	}
+
+	target, ok := moderationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := store.AdjustInventory(r.Context(), target.ID, requestData.Rarity, requestData.Delta, requestData.Reason)
+	if err == db.ErrNotFound {
+		writeJSON(w, http.StatusNotFound, "Utilisateur introuvable", nil)
+		return
+	}
+	if err == db.ErrInsufficientBalance {
+		writeJSON(w, http.StatusConflict, "La quantité ne peut pas devenir négative", nil)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la mise à jour de l'inventaire", nil)
+		return
+	}
+
+	recordAudit(r, "user.inventory", user.Username, map[string]interface{}{"rarity": requestData.Rarity, "delta": requestData.Delta, "reason": requestData.Reason})
+	writeJSON(w, http.StatusOK, "Inventaire mis à jour", user.Inventory)
+}
+
+// ================== Verrouillages de connexion ==================
+
+// AdminLockoutsHandler liste les noms d'utilisateurs et adresses IP ayant des échecs de connexion en cours
+func AdminLockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	users, ips := loginThrottles()
+	writeJSON(w, http.StatusOK, "Verrouillages récupérés avec succès", map[string]interface{}{
+		"users": users.Status(),
+		"ips":   ips.Status(),
+	})
+}
+
+// AdminUnlockUserHandler efface les échecs de connexion d'un utilisateur
+func AdminUnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	target, err := store.GetUserByID(r.Context(), mux.Vars(r)["userid"])
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, "Utilisateur introuvable", nil)
+		return
+	}
+
+	users, _ := loginThrottles()
+	users.Success(usernameKey(target.Username))
+
+	recordAudit(r, "user.unlock", target.Username, nil)
+	writeJSON(w, http.StatusOK, "Utilisateur déverrouillé", nil)
+}
+
+// AdminUnlockIPHandler efface les échecs de connexion d'une adresse IP
+func AdminUnlockIPHandler(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	_, ips := loginThrottles()
+	ips.Success(ip)
+
+	recordAudit(r, "ip.unlock", ip, nil)
+	writeJSON(w, http.StatusOK, "Adresse IP déverrouillée", nil)
+}
+
+// ================== Catégories ==================
+
+// AdminListCategoriesHandler liste toutes les catégories, y compris celles masquées
+func AdminListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := store.GetUserCategories(r.Context(), r.URL.Query().Get("username"), true)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération des catégories", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Catégories récupérées avec succès", categories)
+}
+
+// renvoie un handler qui masque (hidden = true) ou rend visible une catégorie
+func adminSetCategoryHidden(hidden bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		categoryName := mux.Vars(r)["category"]
+		err := store.SetCategoryHidden(r.Context(), categoryName, hidden)
+		if err == db.ErrNotFound {
+			writeJSON(w, http.StatusNotFound, "La catégorie n'existe pas", nil)
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, "Erreur lors de la mise à jour de la catégorie", nil)
+			return
+		}
+
+		action, message := "category.unhide", "Catégorie visible"
+		if hidden {
+			action, message = "category.hide", "Catégorie masquée"
+		}
+		recordAudit(r, action, categoryName, nil)
+		writeJSON(w, http.StatusOK, message, nil)
+	}
+}
+
+// AdminHideCategoryHandler masque une catégorie aux joueurs
+var AdminHideCategoryHandler = adminSetCategoryHidden(true)
+
+// AdminUnhideCategoryHandler rend une catégorie de nouveau visible
+var AdminUnhideCategoryHandler = adminSetCategoryHidden(false)
+
+// AdminDeleteCategoryHandler supprime définitivement une catégorie
+func AdminDeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryName := mux.Vars(r)["category"]
+	category, err := store.GetCategoryByName(r.Context(), categoryName)
+	if err == db.ErrNotFound {
+		writeJSON(w, http.StatusNotFound, "La catégorie n'existe pas", nil)
+		return
+	}
+	if err == nil {
+		err = store.DeleteCategory(r.Context(), categoryName)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la suppression de la catégorie", nil)
+		return
+	}
+
+	recordAudit(r, "category.delete", categoryName, map[string]interface{}{"owner": category.Username, "questions": len(category.Questions)})
+	writeJSON(w, http.StatusOK, "Catégorie supprimée", nil)
+}
+
+// ================== Quiz ==================
+
+// AdminFinishQuizHandler termine de force un quiz bloqué, sans attribuer de récompense
+func AdminFinishQuizHandler(w http.ResponseWriter, r *http.Request) {
+	quiz, err := store.GetQuizByID(r.Context(), mux.Vars(r)["quizid"])
+	if err == db.ErrNotFound {
+		writeJSON(w, http.StatusNotFound, "Quiz introuvable", nil)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération du quiz", nil)
+		return
+	}
+
+	// la fermeture échoue si le joueur a terminé le quiz entre-temps
+	err = store.CloseQuiz(r.Context(), quiz.ID, model.QuizAbandoned, time.Now())
+	if err == db.ErrNoChange {
+		writeJSON(w, http.StatusConflict, "Le quiz est déjà terminé", nil)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la mise à jour du quiz", nil)
+		return
+	}
+	// compté comme un abandon, comme un quiz abandonné par le joueur ou expiré
+	if err = store.AddAbandonedQuiz(r.Context(), quiz.Username); err != nil {
+		log.Printf("Erreur lors de la mise à jour des statistiques de %s : %v", quiz.Username, err)
+	}
+
+	recordAudit(r, "quiz.finish", quiz.ID, map[string]interface{}{"username": quiz.Username, "question": quiz.Number_question})
+	writeJSON(w, http.StatusOK, "Quiz terminé", nil)
+}
+
+// ================== Journal d'audit ==================
+
+// AdminAuditHandler retourne le journal d'audit paginé (?page=1&limit=20)
+func AdminAuditHandler(w http.ResponseWriter, r *http.Request) {
+	_, limit, skip := pagination(r)
+	entries, err := store.ListAuditEntries(r.Context(), skip, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération du journal", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Journal récupéré avec succès", entries)
+}
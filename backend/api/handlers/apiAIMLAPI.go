@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -10,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"quizmaster/config"
 	"quizmaster/model"
 )
 
@@ -65,86 +69,105 @@ func ChatHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Récupérer les clés API depuis .env
-	apiKeys := strings.Split(os.Getenv("AIMLAPI_KEYS"), ",")
-	if len(apiKeys) == 0 {
+	content, err := askAIMLAPI(r.Context(), []Message{
+		{Role: "system", Content: "Tu es un chatbot utile."},
+		{Role: "user", Content: req.Message},
+	})
+	if err == errNoAIMLAPIKey {
 		json.NewEncoder(w).Encode(model.ApiResponse{
 			Status:  http.StatusInternalServerError,
 			Message: "Aucune clé API configurée",
 		})
 		return
 	}
+	if err != nil {
+		json.NewEncoder(w).Encode(model.ApiResponse{
+			Status:  http.StatusInternalServerError,
+			Message: "Échec de la réponse AI après avoir essayé toutes les clés",
+			Data:    err,
+		})
+		return
+	}
 
-	// Configurer le client HTTP avec timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	// Envoyer la réponse
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ApiResponse{
+		Status:  http.StatusOK,
+		Message: "Réponse générée avec succès",
+		Data:    content,
+	})
+}
+
+// aucune clé AIMLAPI_KEYS n'est configurée
+var errNoAIMLAPIKey = errors.New("aucune clé API AIMLAPI configurée")
+
+// client HTTP partagé des appels à AIMLAPI
+var aimlClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+// envoie une conversation à AIMLAPI et retourne la réponse générée.
+// Les clés de AIMLAPI_KEYS sont essayées l'une après l'autre jusqu'à un succès.
+func askAIMLAPI(ctx context.Context, messages []Message) (string, error) {
+	var apiKeys []string
+	for _, apiKey := range strings.Split(os.Getenv("AIMLAPI_KEYS"), ",") {
+		if apiKey = strings.TrimSpace(apiKey); apiKey != "" {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+	if len(apiKeys) == 0 {
+		return "", errNoAIMLAPIKey
 	}
 
 	// Préparer la requête AIMLAPI
 	aimlReq := AIMLAPIRequest{
-		Model: "gpt-3.5-turbo",
-		Messages: []Message{
-			{Role: "system", Content: "Tu es un chatbot utile."},
-			{Role: "user", Content: req.Message},
-		},
+		Model:    config.String("AIMLAPI_MODEL", "gpt-3.5-turbo"),
+		Messages: messages,
 	}
+	aimlReqBody, _ := json.Marshal(aimlReq)
 
-	var response AIMLAPIResponse
 	var lastErr error
 
 	// Essayer chaque clé API jusqu'à succès ou épuisement
 	for _, apiKey := range apiKeys {
-		aimlReqBody, _ := json.Marshal(aimlReq)
-
-		httpReq, err := http.NewRequest("POST", "https://api.aimlapi.com/v1/chat/completions", strings.NewReader(string(aimlReqBody)))
-		if err != nil {
-			lastErr = err
-			log.Printf("Erreur lors de la création de la requête AIMLAPI: %v", err)
-			continue
+		content, err := sendAIMLAPIRequest(ctx, apiKey, aimlReqBody)
+		if err == nil {
+			return content, nil
 		}
-
-		httpReq.Header.Set("Content-Type", "application/json")
-		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
-
-		resp, err := client.Do(httpReq)
-		if err != nil {
-			lastErr = err
-			log.Printf("Erreur lors de la requête vers AIMLAPI: %v", err)
-			continue
+		lastErr = err
+		log.Printf("Erreur lors de la requête vers AIMLAPI: %v", err)
+		if ctx.Err() != nil {
+			break
 		}
-		defer resp.Body.Close()
+	}
+	return "", lastErr
+}
 
-		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-			body, _ := io.ReadAll(resp.Body)
-			lastErr = fmt.Errorf("code de statut inattendu: %d, corps: %s", resp.StatusCode, string(body))
-			log.Printf("Erreur - %v", lastErr)
-			continue
-		}
+func sendAIMLAPIRequest(ctx context.Context, apiKey string, body []byte) (string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", config.String("AIMLAPI_URL", "https://api.aimlapi.com/v1/chat/completions"), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
 
-		if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			lastErr = err
-			log.Printf("Erreur lors du décodage de la réponse AIMLAPI: %v", err)
-			continue
-		}
+	resp, err := aimlClient.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
 
-		// Succès, on sort de la boucle
-		break
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("code de statut inattendu: %d, corps: %s", resp.StatusCode, string(body))
 	}
 
+	var response AIMLAPIResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("décodage de la réponse AIMLAPI: %w", err)
+	}
 	if len(response.Choices) == 0 {
-		json.NewEncoder(w).Encode(model.ApiResponse{
-			Status:  http.StatusInternalServerError,
-			Message: "Échec de la réponse AI après avoir essayé toutes les clés",
-			Data:    lastErr,
-		})
-		return
+		return "", errors.New("réponse AIMLAPI vide")
 	}
-
-	// Envoyer la réponse
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(model.ApiResponse{
-		Status:  http.StatusOK,
-		Message: "Réponse générée avec succès",
-		Data:    response.Choices[0].Message.Content,
-	})
+	return response.Choices[0].Message.Content, nil
 }
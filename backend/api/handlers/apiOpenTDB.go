@@ -3,17 +3,20 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
-	"io/ioutil"
 	"log"
-	"quizmaster/db"
 
 	"net/http"
+	"net/url"
 	"quizmaster/model"
+	"strconv"
+	"time"
 )
 
-var categoryMap = map[string]string{
+// catégories connues d'Open Trivia DB, utilisées tant que la liste n'a pas pu être téléchargée
+var defaultCategoryMap = map[string]string{
 	"General Knowledge":      "9",
 	"Books":                  "10",
 	"Film":                   "11",
@@ -58,35 +61,199 @@ func toStringSlice(input interface{}) ([]string, error) {
 	return result, nil
 }
 
-// récuperation d'un quizz par un API externe
-func GenerateQuiz(userName string, category string) model.Quiz {
-	log.Println("Réception d'une requête GET sur /getQuizByExternalAPI")
-	url := "https://opentdb.com/api.php?amount=10&category=" + categoryMap[category] + "&difficulty=easy&type=multiple"
-	resp, err := http.Get(url)
-	if err != nil {
-		log.Println("Erreur lors de la récupération du quizz")
+// paramètres d'un quiz généré par Open Trivia DB
+type QuizOptions struct {
+	Amount     int
+	Difficulty string
+	Type       string
+	TimeLimit  int
+	Scoring    string
+}
+
+// lit et valide les paramètres amount (1-50, 10 par défaut), difficulty
+// (easy, medium, hard ou any, easy par défaut), type (multiple ou boolean, multiple par défaut)
+// timeLimit (secondes par question, sans limite par défaut) et scoring (classic ou competitive, classic par défaut)
+func parseQuizOptions(r *http.Request) (QuizOptions, error) {
+	query := r.URL.Query()
+	options := QuizOptions{Amount: 10, Difficulty: model.DifficultyEasy, Type: model.TypeMultiple, Scoring: model.ScoringClassic}
+
+	if amount := query.Get("amount"); amount != "" {
+		n, err := strconv.Atoi(amount)
+		if err != nil || n < 1 || n > 50 {
+			return options, fmt.Errorf("Le nombre de questions doit être compris entre 1 et 50")
+		}
+		options.Amount = n
 	}
-	defer resp.Body.Close()
 
-	var quiz model.Quiz
+	if difficulty := query.Get("difficulty"); difficulty != "" {
+		switch difficulty {
+		case model.DifficultyAny, model.DifficultyEasy, model.DifficultyMedium, model.DifficultyHard:
+			options.Difficulty = difficulty
+		default:
+			return options, fmt.Errorf("Difficulté invalide (easy, medium, hard ou any)")
+		}
+	}
+
+	if questionType := query.Get("type"); questionType != "" {
+		switch questionType {
+		case model.TypeMultiple, model.TypeBoolean:
+			options.Type = questionType
+		default:
+			return options, fmt.Errorf("Type de question invalide (multiple ou boolean)")
+		}
+	}
+	if timeLimit := query.Get("timeLimit"); timeLimit != "" {
+		n, err := strconv.Atoi(timeLimit)
+		if err != nil {
+			return options, fmt.Errorf("Limite de temps invalide")
+		}
+		if err = validateTimeLimit(n); err != nil {
+			return options, err
+		}
+		options.TimeLimit = n
+	}
 
-	jsonData, err := ioutil.ReadAll(resp.Body)
+	scoring, err := parseScoring(query.Get("scoring"))
 	if err != nil {
-		log.Println("Erreur lors de la lecture du body")
+		return options, err
 	}
+	options.Scoring = scoring
+	return options, nil
+}
 
-	var jsonDataMap map[string]interface{}
-	err = json.Unmarshal(jsonData, &jsonDataMap)
+// crée un token de session pour l'utilisateur et l'enregistre.
+// En cas d'échec le quiz est demandé sans token (des questions peuvent se répéter).
+func newUserOpenTDBToken(ctx context.Context, user *model.User) string {
+	token, err := requestOpenTDBToken(ctx)
 	if err != nil {
-		log.Println("Erreur lors de l'unmarshalling du JSON")
+		log.Printf("Erreur lors de la création du token Open Trivia DB de %s : %v", user.Username, err)
+		return ""
+	}
+	if err = store.SetUserOpenTDBToken(ctx, user.ID, token); err != nil {
+		log.Printf("Erreur lors de l'enregistrement du token Open Trivia DB de %s : %v", user.Username, err)
 	}
+	user.OpenTDBToken = token
+	return token
+}
 
-	result, ok := jsonDataMap["results"].([]interface{})
+// récuperation d'un quizz par un API externe.
+// Les questions sont tirées de la réserve locale si elle contient assez de questions que
+// l'utilisateur n'a pas encore vues. Sinon elles sont demandées à Open Trivia DB avec le token
+// de session de l'utilisateur, et ajoutées à la réserve. Si Open Trivia DB est indisponible,
+// la réserve ne suffit pas et le quiz est refusé (OpenTDBEmpty).
+func GenerateQuiz(ctx context.Context, user model.User, category string, options QuizOptions) (model.Quiz, error) {
+	var quiz model.Quiz
+
+	questions := questionsFromPool(ctx, user, category, options)
+	if questions == nil {
+		fetched, err := fetchOpenTDBQuestions(ctx, user, category, options)
+		if openTDBUnavailable(err) {
+			log.Printf("Open Trivia DB indisponible (%v) et réserve insuffisante pour %d question(s)", err, options.Amount)
+			return quiz, &OpenTDBError{Kind: OpenTDBEmpty, Err: err}
+		}
+		if err != nil {
+			return quiz, err
+		}
+		addToPool(ctx, fetched)
+		for _, question := range fetched {
+			questions = append(questions, question.Question)
+		}
+	}
+
+	// Mélanger les questions et leurs réponses
+	quiz.Questions = Shuffle(questions)
+	queueExplanations(quiz.Questions)
+
+	quiz.Username = user.Username
+	quiz.Status = model.QuizInProgress
+	quiz.Mode = model.QuizModeOpenTDB
+	quiz.Category = category
+	quiz.CreatedAt = time.Now()
+	quiz.Mark = 0
+	quiz.Finish = false
+	quiz.Number_question = 0
+	quiz.Amount = len(quiz.Questions)
+	quiz.Difficulty = options.Difficulty
+	quiz.Type = options.Type
+	quiz.TimeLimit = options.TimeLimit
+	quiz.Scoring = options.Scoring
+
+	return quiz, nil
+}
+
+// demande les questions du quiz à Open Trivia DB avec le token de session de l'utilisateur
+func fetchOpenTDBQuestions(ctx context.Context, user model.User, category string, options QuizOptions) ([]model.PoolQuestion, error) {
+	params := url.Values{}
+	params.Set("amount", strconv.Itoa(options.Amount))
+	categoryID, ok := externalCategoryID(ctx, category)
 	if !ok {
-		log.Println("Erreur lors de la récupération des questions")
+		return nil, &OpenTDBError{Kind: OpenTDBInvalidParameter, Err: fmt.Errorf("catégorie inconnue : %s", category)}
+	}
+	params.Set("category", strconv.Itoa(categoryID))
+	if options.Difficulty != model.DifficultyAny {
+		params.Set("difficulty", options.Difficulty)
+	}
+	params.Set("type", options.Type)
+
+	token := user.OpenTDBToken
+	if token == "" {
+		token = newUserOpenTDBToken(ctx, &user)
 	}
 
-	for _, question := range result {
+	var response openTDBResponse
+	for attempt := 0; ; attempt++ {
+		params.Del("token")
+		if token != "" {
+			params.Set("token", token)
+		}
+		response = openTDBResponse{}
+		if err := openTDBGet(ctx, "/api.php", params, &response); err != nil {
+			log.Println("Erreur lors de la récupération du quizz :", err)
+			return nil, err
+		}
+		if attempt > 0 || (response.ResponseCode != openTDBTokenNotFound && response.ResponseCode != openTDBTokenEmpty) {
+			break
+		}
+
+		if response.ResponseCode == openTDBTokenNotFound {
+			// le token a expiré (6 heures d'inactivité), on en demande un nouveau
+			token = newUserOpenTDBToken(ctx, &user)
+			continue
+		}
+		// toutes les questions correspondant aux paramètres ont déjà été servies
+		log.Printf("Token Open Trivia DB de %s épuisé, réinitialisation", user.Username)
+		if err := resetOpenTDBToken(ctx, token); err != nil {
+			log.Printf("Erreur lors de la réinitialisation du token Open Trivia DB : %v", err)
+			token = newUserOpenTDBToken(ctx, &user)
+		}
+	}
+
+	switch response.ResponseCode {
+	case openTDBSuccess:
+	case openTDBNoResults, openTDBTokenEmpty:
+		return nil, &OpenTDBError{Kind: OpenTDBEmpty}
+	case openTDBInvalidParameter:
+		return nil, &OpenTDBError{Kind: OpenTDBInvalidParameter}
+	default:
+		return nil, &OpenTDBError{Kind: OpenTDBDecode, Err: fmt.Errorf("code de réponse inattendu : %d", response.ResponseCode)}
+	}
+	if len(response.Results) == 0 {
+		return nil, &OpenTDBError{Kind: OpenTDBEmpty}
+	}
+
+	questions := parseOpenTDBResults(category, response.Results)
+	// aucune question exploitable : un quiz vide ne doit pas être enregistré
+	if len(questions) == 0 {
+		return nil, &OpenTDBError{Kind: OpenTDBDecode, Err: errors.New("aucune question valide dans la réponse")}
+	}
+	return questions, nil
+}
+
+// convertit les résultats d'Open Trivia DB en questions, les entrées mal formées sont ignorées
+func parseOpenTDBResults(category string, results []interface{}) []model.PoolQuestion {
+	var questions []model.PoolQuestion
+	now := time.Now()
+	for _, question := range results {
 		questionMap, ok := question.(map[string]interface{})
 		if !ok {
 			log.Println("Erreur lors de la récupération de la question")
@@ -115,22 +282,51 @@ func GenerateQuiz(userName string, category string) model.Quiz {
 
 		allAnswers := append(incorrectAnswers, html.UnescapeString(correctAnswer))
 
-		quiz.Questions = append(quiz.Questions, model.Question{
-			QuestionText:    html.UnescapeString(questionText),
-			Responses:       allAnswers,
-			ResponseCorrect: html.UnescapeString(correctAnswer),
+		difficulty, _ := questionMap["difficulty"].(string)
+		questionType, _ := questionMap["type"].(string)
+		kind := model.QuestionSingle
+		if questionType == model.TypeBoolean {
+			kind = model.QuestionBoolean
+		}
+		questions = append(questions, model.PoolQuestion{
+			Category:   category,
+			Difficulty: difficulty,
+			Type:       questionType,
+			Question: model.Question{
+				Type:            kind,
+				Source:          openTDBSource,
+				QuestionText:    html.UnescapeString(questionText),
+				Responses:       allAnswers,
+				ResponseCorrect: html.UnescapeString(correctAnswer),
+			},
+			FetchedAt: now,
 		})
 	}
+	return questions
+}
 
-	// Mélanger les questions et leurs réponses
-	quiz.Questions = Shuffle(quiz.Questions)
-
-	quiz.Username = userName
-	quiz.Mark = 0
-	quiz.Finish = false
-	quiz.Number_question = 0
+// traduit une erreur de GenerateQuiz en réponse HTTP
+func writeOpenTDBError(w http.ResponseWriter, err error) {
+	var openTDBErr *OpenTDBError
+	if !errors.As(err, &openTDBErr) {
+		http.Error(w, "Erreur lors de la génération du quiz", http.StatusInternalServerError)
+		return
+	}
 
-	return quiz
+	switch openTDBErr.Kind {
+	case OpenTDBTimeout:
+		http.Error(w, "Le service de questions ne répond pas, réessayez plus tard", http.StatusGatewayTimeout)
+	case OpenTDBEmpty:
+		http.Error(w, "Pas assez de questions disponibles pour ces paramètres", http.StatusServiceUnavailable)
+	case OpenTDBRateLimited:
+		// Open Trivia DB n'accepte qu'une requête toutes les 5 secondes par adresse IP
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Trop de requêtes, réessayez dans quelques secondes", http.StatusServiceUnavailable)
+	case OpenTDBInvalidParameter:
+		http.Error(w, "Paramètres refusés par le service de questions", http.StatusBadRequest)
+	default:
+		http.Error(w, "Le service de questions a renvoyé une erreur", http.StatusBadGateway)
+	}
 }
 
 func GenerateQuizHandler(w http.ResponseWriter, r *http.Request) {
@@ -141,29 +337,48 @@ func GenerateQuizHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.URL.Query().Get("username")
 	category := r.URL.Query().Get("categoryname")
-
-	if username == "" || category == "" {
+	if category == "" {
 		http.Error(w, "Paramètres manquants", http.StatusBadRequest)
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
+	if _, ok := externalCategoryID(r.Context(), category); !ok {
+		http.Error(w, "Catégorie inconnue", http.StatusBadRequest)
+		return
+	}
 
-	boolexist, onGoingQuiz := db.OnGoingQuiz(client, username)
+	options, err := parseQuizOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := requireUser(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+	username := user.Username
+
+	boolexist, onGoingQuiz := store.OnGoingQuiz(r.Context(), username, model.QuizModeOpenTDB, category)
 	if boolexist {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusOK)
 		log.Println("Quiz récupéré avec succès")
-		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz récupéré avec succès", Data: onGoingQuiz})
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz récupéré avec succès", Data: newQuizView(onGoingQuiz)})
 		return
 	}
 
-	quiz := GenerateQuiz(username, category)
+	quiz, err := GenerateQuiz(r.Context(), user, category, options)
+	if err != nil {
+		log.Printf("Erreur lors de la génération du quiz : %v", err)
+		writeOpenTDBError(w, err)
+		return
+	}
 
-	quiz, err := db.CreateQuiz(client, quiz)
+	// le chronomètre de la première question démarre à l'envoi du quiz
+	serveQuestion(&quiz, time.Now())
+	quiz, err = store.CreateQuiz(r.Context(), quiz)
 	if err != nil {
 		http.Error(w, "Erreur lors de l'insertion du quiz", http.StatusInternalServerError)
 		return
@@ -171,5 +386,5 @@ func GenerateQuizHandler(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz généré avec succès", Data: quiz})
+	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz généré avec succès", Data: newQuizView(quiz)})
 }
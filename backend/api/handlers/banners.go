@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"quizmaster/db"
+	"quizmaster/model"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// BannerView est une bannière telle que présentée aux joueurs, avec ses probabilités publiées
+type BannerView struct {
+	model.Banner
+	Odds  []RarityOdds `json:"odds"`
+	Pulls []PullView   `json:"pulls"`
+}
+
+// RarityOdds est la probabilité d'obtenir une rareté à chaque tirage, en pourcentage
+type RarityOdds struct {
+	Rarity      int     `json:"rarity"`
+	Probability float64 `json:"probability"`
+	Featured    bool    `json:"featured,omitempty"`
+}
+
+// PullView est un tirage proposé, avec sa réduction par rapport aux tirages unitaires
+type PullView struct {
+	model.PullOption
+	// réduction en pourcentage, 0 sans tirage unitaire pour comparer
+	Discount int `json:"discount"`
+}
+
+// identifiant d'une bannière : minuscules, chiffres et tirets
+var bannerIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// construit la vue publique d'une bannière
+func newBannerView(banner model.Banner) BannerView {
+	view := BannerView{Banner: banner}
+
+	total := 0.0
+	for _, weight := range banner.Weights {
+		total += weight.Weight
+	}
+	for _, weight := range banner.Weights {
+		view.Odds = append(view.Odds, RarityOdds{
+			Rarity:      weight.Rarity,
+			Probability: math.Round(weight.Weight/total*10000) / 100,
+			Featured:    containsInt(banner.Featured, weight.Rarity),
+		})
+	}
+
+	single := 0
+	for _, pull := range banner.Pulls {
+		if pull.Quantity == 1 {
+			single = pull.Price
+		}
+	}
+	for _, pull := range banner.Pulls {
+		item := PullView{PullOption: pull}
+		if single > 0 && pull.Quantity > 1 {
+			full := single * pull.Quantity
+			item.Discount = int(math.Round(float64(full-pull.Price) * 100 / float64(full)))
+		}
+		view.Pulls = append(view.Pulls, item)
+	}
+	return view
+}
+
+// vérifie une bannière avant son enregistrement
+func validateBanner(banner *model.Banner) error {
+	banner.Name = strings.TrimSpace(banner.Name)
+	if !bannerIDPattern.MatchString(banner.ID) {
+		return fmt.Errorf("identifiant de bannière invalide (minuscules, chiffres et tirets)")
+	}
+	if banner.Name == "" {
+		return fmt.Errorf("le nom de la bannière est requis")
+	}
+	if len(banner.Weights) == 0 {
+		return fmt.Errorf("la bannière doit avoir au moins une rareté")
+	}
+	rarities := make(map[int]bool)
+	for _, weight := range banner.Weights {
+		if weight.Rarity < 3 || weight.Rarity > 6 {
+			return fmt.Errorf("rareté %d invalide (3 à 6)", weight.Rarity)
+		}
+		if rarities[weight.Rarity] {
+			return fmt.Errorf("la rareté %d est en double", weight.Rarity)
+		}
+		if weight.Weight <= 0 || math.IsInf(weight.Weight, 0) || math.IsNaN(weight.Weight) {
+			return fmt.Errorf("le poids de la rareté %d doit être positif", weight.Rarity)
+		}
+		rarities[weight.Rarity] = true
+	}
+	if len(banner.Pulls) == 0 {
+		return fmt.Errorf("la bannière doit proposer au moins un tirage")
+	}
+	quantities := make(map[int]bool)
+	for _, pull := range banner.Pulls {
+		if pull.Quantity < 1 || pull.Quantity > 100 {
+			return fmt.Errorf("nombre de tirages %d invalide (1 à 100)", pull.Quantity)
+		}
+		if quantities[pull.Quantity] {
+			return fmt.Errorf("le tirage de %d antisèches est en double", pull.Quantity)
+		}
+		if pull.Price < 0 {
+			return fmt.Errorf("le prix du tirage de %d antisèches ne peut pas être négatif", pull.Quantity)
+		}
+		quantities[pull.Quantity] = true
+	}
+	if banner.StartsAt != nil && banner.EndsAt != nil && !banner.EndsAt.After(*banner.StartsAt) {
+		return fmt.Errorf("la fin de la bannière doit être après son début")
+	}
+	for _, rarity := range banner.Featured {
+		if !rarities[rarity] {
+			return fmt.Errorf("la rareté mise en avant %d n'est pas dans la bannière", rarity)
+		}
+	}
+	return nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// BannersHandler liste les bannières ouvertes avec leurs probabilités et leurs prix
+func BannersHandler(w http.ResponseWriter, r *http.Request) {
+	banners, err := db.Banners(r.Context(), store)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération des bannières", nil)
+		return
+	}
+
+	now := time.Now()
+	views := []BannerView{}
+	for _, banner := range banners {
+		if db.BannerActive(banner, now) {
+			views = append(views, newBannerView(banner))
+		}
+	}
+	writeJSON(w, http.StatusOK, "Bannières récupérées avec succès", views)
+}
+
+// AdminListBannersHandler liste toutes les bannières, y compris celles fermées ou à venir
+func AdminListBannersHandler(w http.ResponseWriter, r *http.Request) {
+	banners, err := db.Banners(r.Context(), store)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération des bannières", nil)
+		return
+	}
+
+	views := make([]BannerView, len(banners))
+	for i, banner := range banners {
+		views[i] = newBannerView(banner)
+	}
+	writeJSON(w, http.StatusOK, "Bannières récupérées avec succès", views)
+}
+
+// AdminSaveBannerHandler crée ou remplace la bannière {bannerid}
+func AdminSaveBannerHandler(w http.ResponseWriter, r *http.Request) {
+	var banner model.Banner
+	if err := json.NewDecoder(r.Body).Decode(&banner); err != nil {
+		writeJSON(w, http.StatusBadRequest, "Données invalides", nil)
+		return
+	}
+	banner.ID = mux.Vars(r)["bannerid"]
+	if err := validateBanner(&banner); err != nil {
+		writeJSON(w, http.StatusBadRequest, "Bannière invalide : "+err.Error(), nil)
+		return
+	}
+
+	if err := store.SaveBanner(r.Context(), banner); err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de l'enregistrement de la bannière", nil)
+		return
+	}
+
+	recordAudit(r, "banner.save", banner.ID, map[string]interface{}{"weights": banner.Weights, "pulls": banner.Pulls})
+	writeJSON(w, http.StatusOK, "Bannière enregistrée", newBannerView(banner))
+}
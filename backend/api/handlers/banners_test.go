@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"quizmaster/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateBanner(t *testing.T) {
+	valid := func() model.Banner {
+		return model.Banner{
+			ID:      "noel",
+			Name:    " Bannière de Noël ",
+			Weights: []model.RarityWeight{{Rarity: 3, Weight: 90}, {Rarity: 6, Weight: 10}},
+			Pulls:   []model.PullOption{{Quantity: 1, Price: 100}, {Quantity: 10, Price: 900}},
+		}
+	}
+	start := time.Now()
+	end := start.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		change  func(*model.Banner)
+		wantErr string
+	}{
+		{"bannière valide", func(b *model.Banner) {}, ""},
+		{"identifiant invalide", func(b *model.Banner) { b.ID = "Noël" }, "identifiant"},
+		{"nom vide", func(b *model.Banner) { b.Name = "  " }, "nom"},
+		{"sans rareté", func(b *model.Banner) { b.Weights = nil }, "au moins une rareté"},
+		{"rareté hors limites", func(b *model.Banner) { b.Weights[0].Rarity = 7 }, "rareté 7 invalide"},
+		{"rareté en double", func(b *model.Banner) { b.Weights[1].Rarity = 3 }, "en double"},
+		{"poids nul", func(b *model.Banner) { b.Weights[1].Weight = 0 }, "positif"},
+		{"sans tirage", func(b *model.Banner) { b.Pulls = nil }, "au moins un tirage"},
+		{"nombre de tirages invalide", func(b *model.Banner) { b.Pulls[1].Quantity = 101 }, "invalide"},
+		{"tirage en double", func(b *model.Banner) { b.Pulls[1].Quantity = 1 }, "en double"},
+		{"prix négatif", func(b *model.Banner) { b.Pulls[0].Price = -1 }, "négatif"},
+		{"fin avant le début", func(b *model.Banner) { b.StartsAt, b.EndsAt = &start, &end }, "après son début"},
+		{"mise en avant absente", func(b *model.Banner) { b.Featured = []int{5} }, "mise en avant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			banner := valid()
+			tt.change(&banner)
+			err := validateBanner(&banner)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateBanner = %v", err)
+				}
+				if banner.Name != "Bannière de Noël" {
+					t.Errorf("nom non nettoyé : %q", banner.Name)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateBanner = %v, attendu une erreur contenant %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewBannerView(t *testing.T) {
+	view := newBannerView(model.Banner{
+		ID:       "noel",
+		Weights:  []model.RarityWeight{{Rarity: 3, Weight: 2}, {Rarity: 5, Weight: 1}},
+		Pulls:    []model.PullOption{{Quantity: 1, Price: 100}, {Quantity: 10, Price: 900}},
+		Featured: []int{5},
+	})
+
+	wantOdds := []RarityOdds{{Rarity: 3, Probability: 66.67}, {Rarity: 5, Probability: 33.33, Featured: true}}
+	if len(view.Odds) != len(wantOdds) {
+		t.Fatalf("probabilités : %+v", view.Odds)
+	}
+	for i, odds := range wantOdds {
+		if view.Odds[i] != odds {
+			t.Errorf("probabilité %d : %+v, attendu %+v", i, view.Odds[i], odds)
+		}
+	}
+	if len(view.Pulls) != 2 || view.Pulls[0].Discount != 0 || view.Pulls[1].Discount != 10 {
+		t.Errorf("réductions : %+v", view.Pulls)
+	}
+}
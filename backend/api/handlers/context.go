@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"quizmaster/model"
+
+	"github.com/gorilla/mux"
+)
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	sessionKey
+)
+
+// WithUser ajoute l'utilisateur authentifié et sa session au contexte de la requête
+func WithUser(ctx context.Context, user model.User, session model.Session) context.Context {
+	ctx = context.WithValue(ctx, userKey, user)
+	return context.WithValue(ctx, sessionKey, session)
+}
+
+// CurrentUser retourne l'utilisateur authentifié par le middleware
+func CurrentUser(r *http.Request) (model.User, bool) {
+	user, ok := r.Context().Value(userKey).(model.User)
+	return user, ok
+}
+
+// currentSession retourne la session utilisée pour authentifier la requête
+func currentSession(r *http.Request) (model.Session, bool) {
+	session, ok := r.Context().Value(sessionKey).(model.Session)
+	return session, ok
+}
+
+// vérifie que le paramètre {userid} de l'URL désigne l'utilisateur authentifié.
+// Répond 401 ou 403 et retourne false sinon.
+func requireSelf(w http.ResponseWriter, r *http.Request) (model.User, bool) {
+	user, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Authentification requise", http.StatusUnauthorized)
+		return user, false
+	}
+	if userID := mux.Vars(r)["userid"]; userID != "" && userID != user.ID {
+		http.Error(w, "Action non autorisée sur un autre utilisateur", http.StatusForbidden)
+		return user, false
+	}
+	return user, true
+}
+
+// retourne l'utilisateur authentifié. Si le client a envoyé un nom d'utilisateur,
+// il doit correspondre à l'utilisateur authentifié. Répond 401 ou 403 et retourne false sinon.
+func requireUser(w http.ResponseWriter, r *http.Request, claimedUsername string) (model.User, bool) {
+	user, ok := CurrentUser(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusUnauthorized, Message: "Authentification requise"})
+		return user, false
+	}
+	if claimedUsername != "" && claimedUsername != user.Username {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusForbidden, Message: "Action non autorisée pour un autre utilisateur"})
+		return user, false
+	}
+	return user, true
+}
+
+// vérifie que le quiz appartient à l'utilisateur authentifié, sinon répond 403
+func requireQuizOwner(w http.ResponseWriter, user model.User, quiz model.Quiz) bool {
+	if quiz.Username != user.Username {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusForbidden, Message: "Ce quiz appartient à un autre utilisateur"})
+		return false
+	}
+	return true
+}
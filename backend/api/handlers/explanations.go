@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"quizmaster/config"
+	"quizmaster/db"
+	"quizmaster/model"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// longueurs maximales de l'explication et de la source d'une question
+const (
+	maxExplanationLength = 1000
+	maxSourceLength      = 300
+)
+
+// source des questions importées d'Open Trivia DB
+const openTDBSource = "https://opentdb.com"
+
+// questions Open Trivia DB en attente d'explication, nil si les explications automatiques sont désactivées
+var explanationQueue chan model.Question
+
+// vérifie l'explication et la source d'une question.
+// La source est une référence libre, ou une URL http(s) si elle en a la forme.
+func validateExplanation(question *model.Question) error {
+	question.Explanation = strings.TrimSpace(question.Explanation)
+	question.Source = strings.TrimSpace(question.Source)
+
+	if utf8.RuneCountInString(question.Explanation) > maxExplanationLength {
+		return fmt.Errorf("L'explication de la question %q ne doit pas dépasser %d caractères", question.QuestionText, maxExplanationLength)
+	}
+	if utf8.RuneCountInString(question.Source) > maxSourceLength {
+		return fmt.Errorf("La source de la question %q ne doit pas dépasser %d caractères", question.QuestionText, maxSourceLength)
+	}
+	if strings.Contains(question.Source, "://") {
+		u, err := url.Parse(question.Source)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("La source de la question %q n'est pas une URL valide", question.QuestionText)
+		}
+	}
+	return nil
+}
+
+// empreinte identifiant une question et sa bonne réponse dans le cache des explications
+func explanationKey(question model.Question) string {
+	sum := sha256.Sum256([]byte(question.QuestionText + "\x00" + strings.Join(correctAnswers(question), "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// explication d'une question : celle de l'auteur, sinon celle générée et mise en cache
+func questionExplanation(ctx context.Context, question model.Question) string {
+	if question.Explanation != "" {
+		return question.Explanation
+	}
+	if question.Source != openTDBSource {
+		return ""
+	}
+	explanation, err := store.GetExplanation(ctx, explanationKey(question))
+	if err != nil {
+		if err != db.ErrNotFound {
+			log.Printf("Erreur lors de la lecture de l'explication : %v", err)
+		}
+		return ""
+	}
+	return explanation.Text
+}
+
+// StartExplanationWorker génère en tâche de fond les explications des questions Open Trivia DB
+// avec AIMLAPI, une question à la fois. QUESTION_EXPLANATIONS=true l'active (AIMLAPI_KEYS requis).
+func StartExplanationWorker(ctx context.Context) {
+	if !config.Bool("QUESTION_EXPLANATIONS", false) {
+		return
+	}
+	queue := make(chan model.Question, config.Int("QUESTION_EXPLANATIONS_QUEUE", 500))
+	explanationQueue = queue
+	go func() {
+		for {
+			select {
+			case question := <-queue:
+				explainQuestion(ctx, question)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// demande l'explication des questions qui n'en ont pas encore.
+// Les questions sont ignorées si la file est pleine, elles seront reproposées au prochain quiz.
+func queueExplanations(questions []model.Question) {
+	if explanationQueue == nil {
+		return
+	}
+	for _, question := range questions {
+		if question.Explanation != "" {
+			continue
+		}
+		select {
+		case explanationQueue <- question:
+		default:
+			return
+		}
+	}
+}
+
+// génère l'explication d'une question si elle n'est pas déjà en cache
+func explainQuestion(ctx context.Context, question model.Question) {
+	key := explanationKey(question)
+	if _, err := store.GetExplanation(ctx, key); err != db.ErrNotFound {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Duration("QUESTION_EXPLANATIONS_TIMEOUT", 30*time.Second))
+	defer cancel()
+	text, err := askAIMLAPI(ctx, []Message{
+		{Role: "system", Content: "Tu expliques les réponses d'un quiz. Réponds en français, en deux ou trois phrases, sans reformuler la question."},
+		{Role: "user", Content: fmt.Sprintf("Question : %s\nBonne réponse : %s\nExplique pourquoi c'est la bonne réponse.", question.QuestionText, strings.Join(correctAnswers(question), ", "))},
+	})
+	if err != nil {
+		log.Printf("Erreur lors de la génération de l'explication : %v", err)
+		return
+	}
+
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > maxExplanationLength {
+		text = string(runes[:maxExplanationLength])
+	}
+	err = store.SaveExplanation(ctx, model.Explanation{Key: key, Text: text, CreatedAt: time.Now()})
+	if err != nil {
+		log.Printf("Erreur lors de l'enregistrement de l'explication : %v", err)
+	}
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"quizmaster/db"
 	"quizmaster/model"
@@ -14,33 +14,55 @@ func PullHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.Background())
-
 	var requestData struct {
 		Username string `json:"username"`
+		// bannière du tirage, la bannière standard par défaut
+		BannerID string `json:"bannerID"`
 		Quantity int    `json:"quantity"`
 	}
 
-	var message string
-	if requestData.Quantity == 1 {
-		message = "Pull single réussi"
-	} else {
-		message = "Pull multi réussi"
-	}
-
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		http.Error(w, "Erreur lors du décodage de l'id du joueur", http.StatusBadRequest)
 		return
 	}
 
-	result, err := db.GetCheatSheet(client, requestData.Username, requestData.Quantity)
+	user, ok := requireUser(w, r, requestData.Username)
+	if !ok {
+		return
+	}
+
+	if requestData.BannerID == "" {
+		requestData.BannerID = db.DefaultBannerID
+	}
+	result, err := db.GetCheatSheet(r.Context(), store, user.Username, requestData.BannerID, requestData.Quantity)
+	if err == db.ErrBannerUnavailable {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusNotFound, Message: "Bannière introuvable ou fermée"})
+		return
+	}
+	if err == db.ErrInvalidPull {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Nombre de tirages invalide pour cette bannière"})
+		return
+	}
+	if err == db.ErrInsufficientBalance {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Pas assez de pièces"})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: err.Error(), Data: nil})
 		return
 	}
 
+	var message string
+	if requestData.Quantity == 1 {
+		message = "Pull single réussi"
+	} else {
+		message = "Pull multi réussi"
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: message, Data: result})
 }
@@ -51,9 +73,6 @@ func UseCheatSheetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.Background())
-
 	var requestData struct {
 		QuizID string `json:"quizID"`
 		Rarity int    `json:"rarity"`
@@ -64,13 +83,91 @@ func UseCheatSheetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := db.UseCheatSheet(client, requestData.QuizID, requestData.Rarity)
+	user, ok := requireUser(w, r, "")
+	if !ok {
+		return
+	}
+
+	quiz, err := store.GetQuizByID(r.Context(), requestData.QuizID)
+	if err == db.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusNotFound, Message: "Quiz introuvable"})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: err.Error(), Data: nil})
 		return
 	}
+	if !requireQuizOwner(w, user, quiz) {
+		return
+	}
+	if quiz.Finish || quiz.Number_question >= len(quiz.Questions) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Le quiz est déjà terminé"})
+		return
+	}
+
+	ensureAnswerRecords(&quiz)
+	if quiz.Answers[quiz.Number_question].CheatSheet != 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Une antisèche a déjà été utilisée sur cette question"})
+		return
+	}
+	if !hasCheatSheet(user, requestData.Rarity) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Aucune antisèche de cette rareté"})
+		return
+	}
+
+	// l'antisèche est notée sur la question avant d'être retirée de l'inventaire : la vérification
+	// de version empêche deux utilisations sur la même question, et un échec ne coûte rien au joueur
+	marked := quiz
+	marked.Answers = append([]model.AnswerRecord(nil), quiz.Answers...)
+	marked.Answers[quiz.Number_question].CheatSheet = requestData.Rarity
+	marked.CheatSheetsUsed++
+	err = store.UpdateQuiz(r.Context(), marked)
+	if err == db.ErrConflict {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Le quiz a été modifié entre-temps, réessayez"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur lors de l'enregistrement de l'antisèche sur le quiz %s : %v", quiz.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de l'utilisation de l'antisèche"})
+		return
+	}
+
+	result, err := db.UseCheatSheet(r.Context(), store, quiz, requestData.Rarity)
+	if err != nil {
+		// l'inventaire n'a pas été débité : la question redevient libre
+		quiz.Version = marked.Version + 1
+		if revertErr := store.UpdateQuiz(r.Context(), quiz); revertErr != nil {
+			log.Printf("Erreur lors de l'annulation de l'antisèche sur le quiz %s : %v", quiz.ID, revertErr)
+		}
+	}
+	if err == db.ErrInsufficientBalance {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Aucune antisèche de cette rareté"})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de l'utilisation de l'antisèche"})
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "CheatSheet utilisé avec succès", Data: result})
 }
+
+// indique si l'inventaire de l'utilisateur contient une antisèche de la rareté donnée
+func hasCheatSheet(user model.User, rarity int) bool {
+	for _, item := range user.Inventory {
+		if item.Rarity == rarity && item.Quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"quizmaster/config"
+	"quizmaster/model"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// réponses d'une question vrai/faux
+var booleanResponses = []string{"True", "False"}
+
+// type de la question, les questions créées avant les types sont à choix unique
+func questionType(question model.Question) string {
+	if question.Type == "" {
+		return model.QuestionSingle
+	}
+	return question.Type
+}
+
+// vérifie une question avant son enregistrement et complète les valeurs par défaut
+// (type, réponses d'une question vrai/faux)
+func validateQuestion(question *model.Question) error {
+	question.QuestionText = strings.TrimSpace(question.QuestionText)
+	if question.QuestionText == "" {
+		return fmt.Errorf("Le texte de la question est requis")
+	}
+	question.Type = questionType(*question)
+	if err := validateExplanation(question); err != nil {
+		return err
+	}
+
+	switch question.Type {
+	case model.QuestionSingle:
+		if len(question.Responses) < 2 {
+			return fmt.Errorf("La question %q doit proposer au moins 2 réponses", question.QuestionText)
+		}
+		if !contains(question.Responses, question.ResponseCorrect) {
+			return fmt.Errorf("La bonne réponse de la question %q doit faire partie des réponses proposées", question.QuestionText)
+		}
+	case model.QuestionBoolean:
+		if len(question.Responses) == 0 {
+			question.Responses = append([]string(nil), booleanResponses...)
+		}
+		if len(question.Responses) != 2 || !contains(question.Responses, question.ResponseCorrect) {
+			return fmt.Errorf("La question %q doit avoir 2 réponses dont la bonne réponse", question.QuestionText)
+		}
+	case model.QuestionMulti:
+		if len(question.Responses) < 2 {
+			return fmt.Errorf("La question %q doit proposer au moins 2 réponses", question.QuestionText)
+		}
+		if len(question.ResponsesCorrect) == 0 {
+			return fmt.Errorf("La question %q doit avoir au moins une bonne réponse", question.QuestionText)
+		}
+		seen := make(map[string]bool)
+		for _, response := range question.ResponsesCorrect {
+			if !contains(question.Responses, response) {
+				return fmt.Errorf("Les bonnes réponses de la question %q doivent faire partie des réponses proposées", question.QuestionText)
+			}
+			if seen[response] {
+				return fmt.Errorf("La bonne réponse %q de la question %q est en double", response, question.QuestionText)
+			}
+			seen[response] = true
+		}
+		question.ResponseCorrect = ""
+	case model.QuestionFreeText:
+		if normalizeText(question.ResponseCorrect) == "" {
+			return fmt.Errorf("La question %q doit avoir une bonne réponse", question.QuestionText)
+		}
+		if question.Tolerance != nil && (*question.Tolerance < 0 || *question.Tolerance >= 1) {
+			return fmt.Errorf("La tolérance de la question %q doit être comprise entre 0 et 1", question.QuestionText)
+		}
+		// aucune réponse n'est proposée au joueur
+		question.Responses = nil
+	case model.QuestionNumeric:
+		if _, err := parseNumber(question.ResponseCorrect); err != nil {
+			return fmt.Errorf("La bonne réponse de la question %q doit être un nombre", question.QuestionText)
+		}
+		if question.Tolerance != nil && *question.Tolerance < 0 {
+			return fmt.Errorf("La tolérance de la question %q ne peut pas être négative", question.QuestionText)
+		}
+		question.Responses = nil
+	default:
+		return fmt.Errorf("Type de question invalide (single, boolean, multi, text ou numeric)")
+	}
+	return nil
+}
+
+// vérifie toutes les questions d'une catégorie
+func validateQuestions(questions []model.Question) error {
+	for i := range questions {
+		if err := validateQuestion(&questions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// note une réponse selon le type de la question : 1 pour une bonne réponse, 0 pour une
+// mauvaise, une valeur intermédiaire pour une question à choix multiples partiellement juste.
+// answers contient les réponses choisies d'une question à choix multiples.
+func gradeAnswer(question model.Question, answer string, answers []string) float64 {
+	switch questionType(question) {
+	case model.QuestionBoolean:
+		if strings.EqualFold(strings.TrimSpace(answer), question.ResponseCorrect) {
+			return 1
+		}
+	case model.QuestionMulti:
+		return gradeMulti(question, answers)
+	case model.QuestionFreeText:
+		if textMatches(question, answer) {
+			return 1
+		}
+	case model.QuestionNumeric:
+		value, err := parseNumber(answer)
+		expected, errExpected := parseNumber(question.ResponseCorrect)
+		tolerance := 0.0
+		if question.Tolerance != nil {
+			tolerance = *question.Tolerance
+		}
+		if err == nil && errExpected == nil && math.Abs(value-expected) <= tolerance {
+			return 1
+		}
+	default:
+		if answer == question.ResponseCorrect {
+			return 1
+		}
+	}
+	return 0
+}
+
+// chaque bonne réponse cochée rapporte une part, chaque mauvaise en retire une
+func gradeMulti(question model.Question, answers []string) float64 {
+	correct := make(map[string]bool)
+	for _, response := range question.ResponsesCorrect {
+		correct[response] = true
+	}
+	if len(correct) == 0 {
+		return 0
+	}
+
+	hits, misses := 0, 0
+	seen := make(map[string]bool)
+	for _, answer := range answers {
+		if seen[answer] {
+			continue
+		}
+		seen[answer] = true
+		if correct[answer] {
+			hits++
+		} else {
+			misses++
+		}
+	}
+	credit := float64(hits-misses) / float64(len(correct))
+	return math.Max(0, credit)
+}
+
+// compare une réponse libre à la bonne réponse et à ses alias, sans tenir compte de la casse,
+// des accents et de la ponctuation, en acceptant quelques fautes de frappe
+func textMatches(question model.Question, answer string) bool {
+	answer = normalizeText(answer)
+	if answer == "" {
+		return false
+	}
+	// une tolérance de 0 demande une correspondance exacte, seule son absence prend la valeur par défaut
+	tolerance := config.Float("QUIZ_TEXT_TOLERANCE", 0.2)
+	if question.Tolerance != nil {
+		tolerance = *question.Tolerance
+	}
+
+	for _, expected := range append([]string{question.ResponseCorrect}, question.Aliases...) {
+		expected = normalizeText(expected)
+		if expected == "" {
+			continue
+		}
+		allowed := int(tolerance * float64(len([]rune(expected))))
+		if levenshtein(answer, expected) <= allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// supprime les accents
+var removeAccents = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// met une réponse libre en minuscules, sans accents, ponctuation ni espaces superflus
+func normalizeText(text string) string {
+	folded, _, err := transform.String(removeAccents, text)
+	if err != nil {
+		folded = text
+	}
+	folded = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, folded)
+	return strings.Join(strings.Fields(folded), " ")
+}
+
+// distance d'édition entre deux chaînes, en caractères
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// lit un nombre écrit avec un point ou une virgule décimale : une virgule est toujours décimale
+// ("2,500" vaut 2,5). Les milliers ne peuvent être séparés que par des espaces, fines ou
+// insécables ("1 000,5").
+func parseNumber(text string) (float64, error) {
+	invalid := fmt.Errorf("nombre invalide : %q", text)
+	number := strings.TrimSpace(text)
+	sign := ""
+	if strings.HasPrefix(number, "-") || strings.HasPrefix(number, "+") {
+		sign, number = number[:1], number[1:]
+	}
+
+	// un seul séparateur décimal
+	integer, fraction := number, ""
+	if i := strings.IndexAny(number, ".,"); i >= 0 {
+		integer, fraction = number[:i], number[i+1:]
+		if fraction == "" || strings.ContainsAny(fraction, ".,") {
+			return 0, invalid
+		}
+	}
+
+	// groupes de trois chiffres entre les séparateurs de milliers
+	groups := strings.Split(strings.Map(func(r rune) rune {
+		if isThousandsSeparator(r) {
+			return ' '
+		}
+		return r
+	}, integer), " ")
+	for i, group := range groups {
+		if len(groups) > 1 && ((i == 0 && (group == "" || len(group) > 3)) || (i > 0 && len(group) != 3)) {
+			return 0, invalid
+		}
+	}
+	integer = strings.Join(groups, "")
+	if integer == "" && fraction == "" {
+		return 0, invalid
+	}
+	for _, r := range integer + fraction {
+		if r < '0' || r > '9' {
+			return 0, invalid
+		}
+	}
+
+	value, err := strconv.ParseFloat(sign+integer+"."+fraction, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, invalid
+	}
+	return value, nil
+}
+
+// espaces acceptées entre les milliers : normale, insécable, fine et fine insécable
+func isThousandsSeparator(r rune) bool {
+	return r == ' ' || r == '\u00a0' || r == '\u2009' || r == '\u202f'
+}
+
+// bonne(s) réponse(s) d'une question, telles que renvoyées au joueur
+func correctAnswers(question model.Question) []string {
+	if questionType(question) == model.QuestionMulti {
+		return question.ResponsesCorrect
+	}
+	return []string{question.ResponseCorrect}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"quizmaster/model"
+	"testing"
+)
+
+func tolerance(value float64) *float64 {
+	return &value
+}
+
+func TestGradeAnswer(t *testing.T) {
+	single := model.Question{Responses: []string{"Paris", "Lyon"}, ResponseCorrect: "Paris"}
+	boolean := model.Question{Type: model.QuestionBoolean, ResponseCorrect: "True"}
+	multi := model.Question{Type: model.QuestionMulti, Responses: []string{"A", "B", "C", "D"}, ResponsesCorrect: []string{"A", "B"}}
+	text := model.Question{Type: model.QuestionFreeText, ResponseCorrect: "Léonard de Vinci", Aliases: []string{"Vinci"}}
+	exact := model.Question{Type: model.QuestionFreeText, ResponseCorrect: "Mozart", Tolerance: tolerance(0)}
+	numeric := model.Question{Type: model.QuestionNumeric, ResponseCorrect: "1000", Tolerance: tolerance(0.5)}
+	decimal := model.Question{Type: model.QuestionNumeric, ResponseCorrect: "3,14"}
+
+	tests := []struct {
+		name     string
+		question model.Question
+		answer   string
+		answers  []string
+		want     float64
+	}{
+		{"choix unique juste", single, "Paris", nil, 1},
+		{"choix unique faux", single, "Lyon", nil, 0},
+		{"vrai/faux sans casse", boolean, "true", nil, 1},
+		{"choix multiples complet", multi, "", []string{"A", "B"}, 1},
+		{"choix multiples partiel", multi, "", []string{"A"}, 0.5},
+		{"choix multiples avec erreur", multi, "", []string{"A", "C"}, 0},
+		{"choix multiples en double", multi, "", []string{"A", "A"}, 0.5},
+		{"texte sans accents ni casse", text, "leonard de vinci", nil, 1},
+		{"texte avec une faute", text, "Leonard de Vinchi", nil, 1},
+		{"texte alias", text, "vinci", nil, 1},
+		{"texte faux", text, "Michel-Ange", nil, 0},
+		{"texte vide", text, "  ", nil, 0},
+		{"tolérance nulle exacte", exact, "mozart", nil, 1},
+		{"tolérance nulle avec une faute", exact, "mozzart", nil, 0},
+		{"nombre avec séparateur de milliers", numeric, "1 000", nil, 1},
+		{"virgule suivie de trois chiffres", numeric, "1,000", nil, 0},
+		{"nombre dans la tolérance", numeric, "1 000,4", nil, 1},
+		{"nombre hors tolérance", numeric, "1001", nil, 0},
+		{"nombre non fini", numeric, "Inf", nil, 0},
+		{"virgule décimale", decimal, "3.14", nil, 1},
+		{"décimale sans tolérance", decimal, "3,15", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gradeAnswer(tt.question, tt.answer, tt.answers); got != tt.want {
+				t.Errorf("gradeAnswer(%q, %v) = %v, attendu %v", tt.answer, tt.answers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    float64
+		wantErr bool
+	}{
+		{"42", 42, false},
+		{" -2,5 ", -2.5, false},
+		{"3.14", 3.14, false},
+		{".5", 0.5, false},
+		{"2,500", 2.5, false},
+		{"1,000", 1, false},
+		{"1 000", 1000, false},
+		{"1 000 000", 1000000, false},
+		{"1 000,5", 1000.5, false},
+		{"1\u00a0000,5", 1000.5, false},
+		{"1\u2009000.5", 1000.5, false},
+		{"1\u202f000", 1000, false},
+		{"1,000,000", 0, true},
+		{"1.000.000", 0, true},
+		{"1.000,5", 0, true},
+		{"1,000.5", 0, true},
+		{"1'000", 0, true},
+		{"10 00", 0, true},
+		{"1  000", 0, true},
+		{" 1000", 1000, false},
+		{"3,1 4", 0, true},
+		{"5.", 0, true},
+		{"", 0, true},
+		{"-", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"1e5", 0, true},
+		{"0x10", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseNumber(tt.text)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseNumber(%q) = %v, %v ; attendu %v (erreur : %v)", tt.text, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateQuestion(t *testing.T) {
+	tests := []struct {
+		name     string
+		question model.Question
+		wantErr  bool
+	}{
+		{"choix unique", model.Question{QuestionText: "Q", Responses: []string{"A", "B"}, ResponseCorrect: "A"}, false},
+		{"bonne réponse absente", model.Question{QuestionText: "Q", Responses: []string{"A", "B"}, ResponseCorrect: "C"}, true},
+		{"vrai/faux complété", model.Question{QuestionText: "Q", Type: model.QuestionBoolean, ResponseCorrect: "False"}, false},
+		{"choix multiples en double", model.Question{QuestionText: "Q", Type: model.QuestionMulti, Responses: []string{"A", "B"}, ResponsesCorrect: []string{"A", "A"}}, true},
+		{"texte sans tolérance", model.Question{QuestionText: "Q", Type: model.QuestionFreeText, ResponseCorrect: "A"}, false},
+		{"texte tolérance nulle", model.Question{QuestionText: "Q", Type: model.QuestionFreeText, ResponseCorrect: "A", Tolerance: tolerance(0)}, false},
+		{"texte tolérance trop grande", model.Question{QuestionText: "Q", Type: model.QuestionFreeText, ResponseCorrect: "A", Tolerance: tolerance(1)}, true},
+		{"nombre invalide", model.Question{QuestionText: "Q", Type: model.QuestionNumeric, ResponseCorrect: "NaN"}, true},
+		{"tolérance numérique négative", model.Question{QuestionText: "Q", Type: model.QuestionNumeric, ResponseCorrect: "1", Tolerance: tolerance(-1)}, true},
+		{"type inconnu", model.Question{QuestionText: "Q", Type: "essai"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := tt.question
+			if err := validateQuestion(&question); (err != nil) != tt.wantErr {
+				t.Errorf("validateQuestion = %v, erreur attendue : %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"quizmaster/config"
+	"quizmaster/db"
+	"time"
+)
+
+// LedgerReport liste les incohérences entre le journal d'un utilisateur et ses soldes
+type LedgerReport struct {
+	UserID   string   `json:"userId"`
+	Username string   `json:"username"`
+	Entries  int      `json:"entries"`
+	Issues   []string `json:"issues"`
+}
+
+// TransactionsHandler retourne les mouvements de pièces et d'antisèches paginés de l'utilisateur (?page=1&limit=20)
+func TransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireSelf(w, r)
+	if !ok {
+		return
+	}
+
+	_, limit, skip := pagination(r)
+	entries, err := store.ListLedgerEntries(r.Context(), user.ID, skip, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération des transactions", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Transactions récupérées avec succès", entries)
+}
+
+// AdminCheckLedgerHandler rejoue le journal de tous les utilisateurs et retourne les incohérences trouvées
+func AdminCheckLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	reports, err := checkLedgers(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la vérification du journal", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Journal vérifié", reports)
+}
+
+// vérifie le journal de chaque utilisateur, seuls les utilisateurs avec des incohérences sont retournés
+func checkLedgers(ctx context.Context) ([]LedgerReport, error) {
+	users, err := store.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []LedgerReport{}
+	for _, user := range users {
+		entries, err := store.UserLedger(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if issues := db.CheckLedger(user, entries); len(issues) > 0 {
+			reports = append(reports, LedgerReport{UserID: user.ID, Username: user.Username, Entries: len(entries), Issues: issues})
+		}
+	}
+	return reports, nil
+}
+
+// StartLedgerChecker vérifie en tâche de fond, toutes les LEDGER_CHECK_INTERVAL (24h par défaut,
+// 0 pour désactiver), que le journal correspond aux soldes des utilisateurs
+func StartLedgerChecker(ctx context.Context) {
+	interval := config.Duration("LEDGER_CHECK_INTERVAL", 24*time.Hour)
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+			reports, err := checkLedgers(ctx)
+			if err != nil {
+				log.Printf("Vérification du journal : %v", err)
+				continue
+			}
+			for _, report := range reports {
+				log.Printf("Vérification du journal : %s (%s) : %v", report.Username, report.UserID, report.Issues)
+			}
+		}
+	}()
+}
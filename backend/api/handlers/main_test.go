@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"quizmaster/db"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// les handlers journalisent chaque requête
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// useMemoryStore fait utiliser aux handlers un stockage en mémoire vide le temps du test
+func useMemoryStore(t *testing.T) *db.MemoryStore {
+	t.Helper()
+	previous := store
+	memory := db.NewMemoryStore()
+	store = memory
+	t.Cleanup(func() { store = previous })
+	return memory
+}
+
+// fakeOpenTDB imite Open Trivia DB : chaque appel à /api.php renvoie des questions nouvelles
+type fakeOpenTDB struct {
+	mu    sync.Mutex
+	calls map[string]int
+	next  int
+	// réponse HTTP en erreur sur toutes les routes
+	down bool
+}
+
+// startFakeOpenTDB démarre le serveur et le fait utiliser par les handlers le temps du test
+func startFakeOpenTDB(t *testing.T) *fakeOpenTDB {
+	t.Helper()
+	fake := &fakeOpenTDB{calls: map[string]int{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	t.Setenv("OPENTDB_URL", server.URL)
+	t.Setenv("OPENTDB_RETRIES", "0")
+	t.Setenv("OPENTDB_RATE_LIMIT_DELAY", "0s")
+	return fake
+}
+
+func (f *fakeOpenTDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[r.URL.Path]++
+	if f.down {
+		http.Error(w, "indisponible", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/api_token.php":
+		json.NewEncoder(w).Encode(map[string]interface{}{"response_code": 0, "token": "token-test"})
+	case "/api_category.php":
+		json.NewEncoder(w).Encode(map[string]interface{}{"trivia_categories": []map[string]interface{}{
+			{"id": 9, "name": "General Knowledge"},
+			{"id": 10, "name": "Entertainment: Books"},
+		}})
+	case "/api_count.php":
+		json.NewEncoder(w).Encode(map[string]interface{}{"category_id": 9, "category_question_count": map[string]int{
+			"total_question_count": 30, "total_easy_question_count": 10, "total_medium_question_count": 10, "total_hard_question_count": 10,
+		}})
+	case "/api.php":
+		amount, _ := strconv.Atoi(r.URL.Query().Get("amount"))
+		difficulty := r.URL.Query().Get("difficulty")
+		if difficulty == "" {
+			difficulty = "easy"
+		}
+		results := []map[string]interface{}{}
+		for i := 0; i < amount; i++ {
+			f.next++
+			results = append(results, map[string]interface{}{
+				"type":              "multiple",
+				"difficulty":        difficulty,
+				"question":          fmt.Sprintf("Question %d", f.next),
+				"correct_answer":    "A",
+				"incorrect_answers": []string{"B", "C", "D"},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"response_code": 0, "results": results})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// nombre d'appels reçus sur path
+func (f *fakeOpenTDB) count(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[path]
+}
+
+func (f *fakeOpenTDB) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+// useCategories remplace le cache des catégories d'Open Trivia DB le temps du test
+func useCategories(t *testing.T, categories []ExternalCategory) {
+	t.Helper()
+	categoryCache.mu.Lock()
+	categoryCache.categories = categories
+	categoryCache.fetchedAt = time.Now()
+	categoryCache.countsFetchedAt = time.Time{}
+	categoryCache.failedAt = time.Time{}
+	categoryCache.mu.Unlock()
+	t.Cleanup(func() {
+		categoryCache.mu.Lock()
+		categoryCache.categories = nil
+		categoryCache.fetchedAt = time.Time{}
+		categoryCache.countsFetchedAt = time.Time{}
+		categoryCache.failedAt = time.Time{}
+		categoryCache.mu.Unlock()
+	})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"quizmaster/config"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExternalCategory est une catégorie d'Open Trivia DB
+type ExternalCategory struct {
+	ID     int             `json:"id"`
+	Name   string          `json:"name"`
+	Counts *CategoryCounts `json:"counts,omitempty"`
+}
+
+// CategoryCounts est le nombre de questions d'une catégorie, par difficulté
+type CategoryCounts struct {
+	Total  int `json:"total"`
+	Easy   int `json:"easy"`
+	Medium int `json:"medium"`
+	Hard   int `json:"hard"`
+}
+
+type openTDBCategoriesResponse struct {
+	TriviaCategories []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"trivia_categories"`
+}
+
+// api_category.php ne porte pas de code de réponse
+func (r *openTDBCategoriesResponse) responseCode() int { return openTDBSuccess }
+
+type openTDBCountResponse struct {
+	CategoryID int `json:"category_id"`
+	Counts     struct {
+		Total  int `json:"total_question_count"`
+		Easy   int `json:"total_easy_question_count"`
+		Medium int `json:"total_medium_question_count"`
+		Hard   int `json:"total_hard_question_count"`
+	} `json:"category_question_count"`
+}
+
+func (r *openTDBCountResponse) responseCode() int { return openTDBSuccess }
+
+// cache des catégories, renouvelé après OPENTDB_CATEGORIES_TTL (24h par défaut)
+var categoryCache struct {
+	mu              sync.Mutex
+	categories      []ExternalCategory
+	fetchedAt       time.Time
+	countsFetchedAt time.Time
+	// dernier échec, pour ne pas réessayer à chaque requête quand Open Trivia DB est injoignable
+	failedAt time.Time
+	// fermé à la fin du téléchargement en cours, nil s'il n'y en a pas
+	refreshing chan struct{}
+}
+
+// nom court d'une catégorie, tel qu'utilisé par le frontend ("Entertainment: Books" devient "Books")
+func shortCategoryName(name string) string {
+	for _, prefix := range []string{"Entertainment: ", "Science: "} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	return name
+}
+
+// liste construite à partir de defaultCategoryMap
+func defaultCategories() []ExternalCategory {
+	categories := make([]ExternalCategory, 0, len(defaultCategoryMap))
+	for name, id := range defaultCategoryMap {
+		n, _ := strconv.Atoi(id)
+		categories = append(categories, ExternalCategory{ID: n, Name: name})
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories
+}
+
+// télécharge la liste des catégories
+func fetchExternalCategories(ctx context.Context) ([]ExternalCategory, error) {
+	var response openTDBCategoriesResponse
+	if err := openTDBGet(ctx, "/api_category.php", url.Values{}, &response); err != nil {
+		return nil, err
+	}
+	if len(response.TriviaCategories) == 0 {
+		return nil, &OpenTDBError{Kind: OpenTDBEmpty}
+	}
+
+	categories := make([]ExternalCategory, 0, len(response.TriviaCategories))
+	for _, category := range response.TriviaCategories {
+		categories = append(categories, ExternalCategory{ID: category.ID, Name: shortCategoryName(category.Name)})
+	}
+	return categories, nil
+}
+
+// télécharge le nombre de questions de chaque catégorie, s'arrête à la première erreur
+func fetchCategoryCounts(ctx context.Context, categories []ExternalCategory) error {
+	for i := range categories {
+		var response openTDBCountResponse
+		err := openTDBGet(ctx, "/api_count.php", url.Values{"category": {strconv.Itoa(categories[i].ID)}}, &response)
+		if err != nil {
+			return err
+		}
+		categories[i].Counts = &CategoryCounts{
+			Total:  response.Counts.Total,
+			Easy:   response.Counts.Easy,
+			Medium: response.Counts.Medium,
+			Hard:   response.Counts.Hard,
+		}
+	}
+	return nil
+}
+
+// externalCategories retourne les catégories d'Open Trivia DB depuis le cache. Quand le cache est
+// périmé, il est renouvelé en tâche de fond, un seul téléchargement à la fois, et l'ancienne liste
+// est servie en attendant. Sans liste connue, le premier téléchargement est attendu, et
+// defaultCategoryMap est utilisé s'il échoue.
+// withCounts demande en plus le nombre de questions par difficulté.
+func externalCategories(ctx context.Context, withCounts bool) []ExternalCategory {
+	categoryCache.mu.Lock()
+	ttl := config.Duration("OPENTDB_CATEGORIES_TTL", 24*time.Hour)
+	canRetry := time.Since(categoryCache.failedAt) > config.Duration("OPENTDB_CATEGORIES_RETRY", time.Minute)
+	listStale := categoryCache.categories == nil || time.Since(categoryCache.fetchedAt) > ttl
+	countsStale := withCounts && time.Since(categoryCache.countsFetchedAt) > ttl
+	done := categoryCache.refreshing
+	if done == nil && canRetry && (listStale || countsStale) {
+		done = make(chan struct{})
+		categoryCache.refreshing = done
+		go refreshCategories(listStale, withCounts, done)
+	}
+	categories := categoryCache.categories
+	categoryCache.mu.Unlock()
+
+	if categories == nil && done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		categoryCache.mu.Lock()
+		categories = categoryCache.categories
+		categoryCache.mu.Unlock()
+	}
+	if categories == nil {
+		return defaultCategories()
+	}
+	// la liste en cache est remplacée, jamais modifiée : elle peut être copiée sans le verrou
+	return copyCategories(categories)
+}
+
+// télécharge la liste des catégories et/ou leur nombre de questions, hors du verrou du cache,
+// puis remplace la liste en cache. Le téléchargement ne dépend pas de la requête qui l'a déclenché.
+func refreshCategories(fetchList bool, withCounts bool, done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Duration("OPENTDB_CATEGORIES_TIMEOUT", 2*time.Minute))
+	defer func() {
+		cancel()
+		categoryCache.mu.Lock()
+		categoryCache.refreshing = nil
+		categoryCache.mu.Unlock()
+		close(done)
+	}()
+
+	if fetchList {
+		categories, err := fetchExternalCategories(ctx)
+		categoryCache.mu.Lock()
+		if err != nil {
+			categoryCache.failedAt = time.Now()
+		} else {
+			categoryCache.categories = categories
+			categoryCache.fetchedAt = time.Now()
+			categoryCache.countsFetchedAt = time.Time{}
+		}
+		categoryCache.mu.Unlock()
+		if err != nil {
+			log.Printf("Erreur lors du téléchargement des catégories Open Trivia DB : %v", err)
+			return
+		}
+	}
+	if !withCounts {
+		return
+	}
+
+	categoryCache.mu.Lock()
+	categories := copyCategories(categoryCache.categories)
+	categoryCache.mu.Unlock()
+	err := fetchCategoryCounts(ctx, categories)
+	categoryCache.mu.Lock()
+	if err != nil {
+		categoryCache.failedAt = time.Now()
+	} else {
+		categoryCache.categories = categories
+		categoryCache.countsFetchedAt = time.Now()
+	}
+	categoryCache.mu.Unlock()
+	if err != nil {
+		log.Printf("Erreur lors du téléchargement du nombre de questions Open Trivia DB : %v", err)
+	}
+}
+
+func copyCategories(categories []ExternalCategory) []ExternalCategory {
+	result := make([]ExternalCategory, len(categories))
+	for i, category := range categories {
+		if category.Counts != nil {
+			counts := *category.Counts
+			category.Counts = &counts
+		}
+		result[i] = category
+	}
+	return result
+}
+
+// externalCategoryID retourne l'identifiant Open Trivia DB de la catégorie name
+func externalCategoryID(ctx context.Context, name string) (int, bool) {
+	for _, category := range externalCategories(ctx, false) {
+		if category.Name == name {
+			return category.ID, true
+		}
+	}
+	return 0, false
+}
+
+// ExternalCategoriesHandler liste les catégories d'Open Trivia DB avec leur nombre de questions
+func ExternalCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Réception d'une requête GET sur /externalCategories")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Catégories récupérées avec succès", externalCategories(r.Context(), true))
+}
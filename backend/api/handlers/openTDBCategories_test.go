@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExternalCategoriesDownload(t *testing.T) {
+	fake := startFakeOpenTDB(t)
+	useCategories(t, nil)
+	ctx := context.Background()
+
+	// sans liste en cache, le premier téléchargement est attendu
+	categories := externalCategories(ctx, false)
+	if len(categories) != 2 || categories[1].Name != "Books" {
+		t.Fatalf("catégories : %+v", categories)
+	}
+	if id, ok := externalCategoryID(ctx, "Books"); !ok || id != 10 {
+		t.Errorf("identifiant de Books : %d, %v", id, ok)
+	}
+
+	// la liste en cache est servie sans nouvel appel
+	externalCategories(ctx, false)
+	if calls := fake.count("/api_category.php"); calls != 1 {
+		t.Errorf("%d téléchargement(s) de la liste, attendu 1", calls)
+	}
+
+	// le nombre de questions est téléchargé en tâche de fond, l'ancienne liste est servie en attendant
+	if categories = externalCategories(ctx, true); categories[0].Counts != nil {
+		t.Errorf("nombre de questions servi avant la fin du téléchargement : %+v", categories[0])
+	}
+	waitRefresh(t)
+	if categories = externalCategories(ctx, true); categories[0].Counts == nil || categories[0].Counts.Total != 30 {
+		t.Errorf("nombre de questions : %+v", categories[0].Counts)
+	}
+}
+
+func TestExternalCategoriesFallback(t *testing.T) {
+	fake := startFakeOpenTDB(t)
+	useCategories(t, nil)
+	fake.setDown(true)
+	ctx := context.Background()
+
+	// Open Trivia DB injoignable : la liste par défaut est utilisée
+	if categories := externalCategories(ctx, false); len(categories) != len(defaultCategoryMap) {
+		t.Fatalf("%d catégories, attendu la liste par défaut (%d)", len(categories), len(defaultCategoryMap))
+	}
+	// l'échec n'est pas réessayé à chaque requête
+	externalCategories(ctx, false)
+	if calls := fake.count("/api_category.php"); calls != 1 {
+		t.Errorf("%d téléchargement(s) après un échec, attendu 1", calls)
+	}
+
+	// une liste périmée est conservée tant que le téléchargement échoue
+	t.Setenv("OPENTDB_CATEGORIES_RETRY", "0s")
+	useCategories(t, []ExternalCategory{{ID: 9, Name: "General Knowledge"}})
+	categoryCache.mu.Lock()
+	categoryCache.fetchedAt = time.Now().Add(-48 * time.Hour)
+	categoryCache.mu.Unlock()
+	externalCategories(ctx, false)
+	waitRefresh(t)
+	if categories := externalCategories(ctx, false); len(categories) != 1 {
+		t.Errorf("catégories après un échec du renouvellement : %+v", categories)
+	}
+	waitRefresh(t)
+}
+
+// attend la fin du téléchargement des catégories en cours
+func waitRefresh(t *testing.T) {
+	t.Helper()
+	categoryCache.mu.Lock()
+	done := categoryCache.refreshing
+	categoryCache.mu.Unlock()
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("téléchargement des catégories trop long")
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"quizmaster/config"
+	"sync"
+	"time"
+)
+
+// codes de réponse d'Open Trivia DB
+const (
+	openTDBSuccess          = 0
+	openTDBNoResults        = 1
+	openTDBInvalidParameter = 2
+	openTDBTokenNotFound    = 3
+	openTDBTokenEmpty       = 4
+	openTDBRateLimit        = 5
+)
+
+// OpenTDBErrorKind classe les échecs d'un appel à Open Trivia DB
+type OpenTDBErrorKind int
+
+const (
+	OpenTDBNetwork          OpenTDBErrorKind = iota // connexion impossible ou réponse HTTP en erreur
+	OpenTDBTimeout                                  // délai dépassé
+	OpenTDBDecode                                   // réponse illisible
+	OpenTDBEmpty                                    // aucune question disponible pour ces paramètres
+	OpenTDBRateLimited                              // limite de requêtes atteinte
+	OpenTDBInvalidParameter                         // paramètre refusé
+)
+
+// OpenTDBError est l'erreur retournée par GenerateQuiz et les appels à Open Trivia DB
+type OpenTDBError struct {
+	Kind OpenTDBErrorKind
+	Err  error
+}
+
+func (e *OpenTDBError) Error() string {
+	var message string
+	switch e.Kind {
+	case OpenTDBNetwork:
+		message = "Open Trivia DB injoignable"
+	case OpenTDBTimeout:
+		message = "Open Trivia DB ne répond pas"
+	case OpenTDBDecode:
+		message = "réponse d'Open Trivia DB illisible"
+	case OpenTDBEmpty:
+		message = "pas assez de questions disponibles pour ces paramètres"
+	case OpenTDBRateLimited:
+		message = "trop de requêtes vers Open Trivia DB"
+	case OpenTDBInvalidParameter:
+		message = "paramètre refusé par Open Trivia DB"
+	}
+	if e.Err != nil {
+		return message + " : " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *OpenTDBError) Unwrap() error {
+	return e.Err
+}
+
+// indique si un nouvel essai peut réussir
+func (e *OpenTDBError) retryable() bool {
+	return e.Kind == OpenTDBNetwork || e.Kind == OpenTDBTimeout || e.Kind == OpenTDBRateLimited
+}
+
+// IsOpenTDBError indique si err est une OpenTDBError du type kind
+func IsOpenTDBError(err error, kind OpenTDBErrorKind) bool {
+	var openTDBErr *OpenTDBError
+	return errors.As(err, &openTDBErr) && openTDBErr.Kind == kind
+}
+
+// réponses d'Open Trivia DB, toutes portent un code de réponse
+type openTDBReply interface {
+	responseCode() int
+}
+
+type openTDBResponse struct {
+	ResponseCode int           `json:"response_code"`
+	Results      []interface{} `json:"results"`
+}
+
+func (r *openTDBResponse) responseCode() int { return r.ResponseCode }
+
+type openTDBTokenResponse struct {
+	ResponseCode int    `json:"response_code"`
+	Token        string `json:"token"`
+}
+
+func (r *openTDBTokenResponse) responseCode() int { return r.ResponseCode }
+
+// client HTTP partagé, configuré par OPENTDB_TIMEOUT (10s par défaut)
+var (
+	openTDBClientOnce sync.Once
+	openTDBClient     *http.Client
+)
+
+func openTDBHTTPClient() *http.Client {
+	openTDBClientOnce.Do(func() {
+		openTDBClient = &http.Client{Timeout: config.Duration("OPENTDB_TIMEOUT", 10*time.Second)}
+	})
+	return openTDBClient
+}
+
+// effectue une requête GET vers Open Trivia DB (OPENTDB_URL) et décode la réponse JSON dans reply.
+// Les erreurs réseau, les réponses 5xx et les limites de requêtes sont réessayées
+// OPENTDB_RETRIES fois (2 par défaut), avec un délai croissant à partir de OPENTDB_RETRY_DELAY.
+func openTDBGet(ctx context.Context, path string, params url.Values, reply openTDBReply) error {
+	retries := config.Int("OPENTDB_RETRIES", 2)
+	delay := config.Duration("OPENTDB_RETRY_DELAY", 500*time.Millisecond)
+	// Open Trivia DB n'accepte qu'une requête toutes les 5 secondes par adresse IP
+	rateLimitDelay := config.Duration("OPENTDB_RATE_LIMIT_DELAY", 5*time.Second)
+
+	for attempt := 0; ; attempt++ {
+		err := openTDBGetOnce(ctx, path, params, reply)
+		var openTDBErr *OpenTDBError
+		if err == nil || !errors.As(err, &openTDBErr) || !openTDBErr.retryable() || attempt >= retries {
+			return err
+		}
+
+		wait := delay << uint(attempt)
+		if openTDBErr.Kind == OpenTDBRateLimited && wait < rateLimitDelay {
+			wait = rateLimitDelay
+		}
+		log.Printf("Appel à Open Trivia DB échoué (%v), nouvel essai dans %s", err, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return &OpenTDBError{Kind: OpenTDBTimeout, Err: ctx.Err()}
+		}
+	}
+}
+
+func openTDBGetOnce(ctx context.Context, path string, params url.Values, reply openTDBReply) error {
+	apiURL := config.String("OPENTDB_URL", "https://opentdb.com") + path + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := openTDBHTTPClient().Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || isTimeout(err) {
+			return &OpenTDBError{Kind: OpenTDBTimeout, Err: err}
+		}
+		return &OpenTDBError{Kind: OpenTDBNetwork, Err: err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return &OpenTDBError{Kind: OpenTDBRateLimited}
+	case resp.StatusCode != http.StatusOK:
+		return &OpenTDBError{Kind: OpenTDBNetwork, Err: fmt.Errorf("statut HTTP %d", resp.StatusCode)}
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		return &OpenTDBError{Kind: OpenTDBDecode, Err: err}
+	}
+	if reply.responseCode() == openTDBRateLimit {
+		return &OpenTDBError{Kind: OpenTDBRateLimited}
+	}
+	return nil
+}
+
+// indique si err est un dépassement de délai du client HTTP
+func isTimeout(err error) bool {
+	var timeoutErr interface{ Timeout() bool }
+	return errors.As(err, &timeoutErr) && timeoutErr.Timeout()
+}
+
+// demande un nouveau token de session, qui garantit de ne pas recevoir deux fois la même question
+func requestOpenTDBToken(ctx context.Context) (string, error) {
+	var response openTDBTokenResponse
+	err := openTDBGet(ctx, "/api_token.php", url.Values{"command": {"request"}}, &response)
+	if err != nil {
+		return "", err
+	}
+	if response.ResponseCode != openTDBSuccess || response.Token == "" {
+		return "", fmt.Errorf("code de réponse Open Trivia DB inattendu : %d", response.ResponseCode)
+	}
+	return response.Token, nil
+}
+
+// réinitialise le token : toutes les questions peuvent de nouveau être servies
+func resetOpenTDBToken(ctx context.Context, token string) error {
+	var response openTDBTokenResponse
+	err := openTDBGet(ctx, "/api_token.php", url.Values{"command": {"reset"}, "token": {token}}, &response)
+	if err != nil {
+		return err
+	}
+	if response.ResponseCode != openTDBSuccess {
+		return fmt.Errorf("code de réponse Open Trivia DB inattendu : %d", response.ResponseCode)
+	}
+	return nil
+}
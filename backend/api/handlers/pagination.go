@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// au-delà, (page-1)*limit pourrait dépasser la capacité d'un int
+	maxPage = 100000
+)
+
+// lit les paramètres de pagination ?page=1&limit=20 et retourne (page, limit, skip).
+// Une page au-delà de maxPage est ramenée à maxPage.
+func pagination(r *http.Request) (int, int, int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	if page > maxPage {
+		page = maxPage
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return page, limit, (page - 1) * limit
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantLimit int
+		wantSkip  int
+	}{
+		{"", 1, defaultPageSize, 0},
+		{"?page=3&limit=10", 3, 10, 20},
+		{"?page=0&limit=-5", 1, defaultPageSize, 0},
+		{"?page=abc&limit=1000", 1, maxPageSize, 0},
+		{"?page=9223372036854775807&limit=100", maxPage, 100, (maxPage - 1) * 100},
+		{"?page=99999999999999999999", 1, defaultPageSize, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, limit, skip := pagination(httptest.NewRequest("GET", "/"+tt.query, nil))
+			if page != tt.wantPage || limit != tt.wantLimit || skip != tt.wantSkip {
+				t.Errorf("pagination = %d, %d, %d ; attendu %d, %d, %d", page, limit, skip, tt.wantPage, tt.wantLimit, tt.wantSkip)
+			}
+		})
+	}
+}
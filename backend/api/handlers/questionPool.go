@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"quizmaster/config"
+	"quizmaster/model"
+	"strconv"
+	"time"
+)
+
+// tire au hasard options.Amount questions de la réserve locale parmi celles que l'utilisateur
+// n'a pas encore vues. Retourne nil si la réserve n'en contient pas assez.
+func questionsFromPool(ctx context.Context, user model.User, category string, options QuizOptions) []model.Question {
+	seen, err := store.SeenQuestions(ctx, user.Username, model.QuizModeOpenTDB, category)
+	if err != nil {
+		log.Printf("Erreur lors de la lecture des questions déjà vues par %s : %v", user.Username, err)
+		return nil
+	}
+	pooled, err := store.SamplePoolQuestions(ctx, category, options.Difficulty, options.Type, seen, options.Amount)
+	if err != nil {
+		log.Printf("Erreur lors de la lecture de la réserve de questions : %v", err)
+		return nil
+	}
+	if len(pooled) < options.Amount {
+		return nil
+	}
+
+	questions := make([]model.Question, len(pooled))
+	for i, question := range pooled {
+		questions[i] = question.Question
+	}
+	return questions
+}
+
+// ajoute à la réserve les questions reçues d'Open Trivia DB (les doublons sont ignorés)
+func addToPool(ctx context.Context, questions []model.PoolQuestion) {
+	if _, err := store.AddPoolQuestions(ctx, questions); err != nil {
+		log.Printf("Erreur lors de l'ajout à la réserve de questions : %v", err)
+	}
+}
+
+// indique si l'erreur vient d'une indisponibilité d'Open Trivia DB, auquel cas la réserve peut servir
+func openTDBUnavailable(err error) bool {
+	return IsOpenTDBError(err, OpenTDBNetwork) || IsOpenTDBError(err, OpenTDBTimeout) ||
+		IsOpenTDBError(err, OpenTDBRateLimited) || IsOpenTDBError(err, OpenTDBDecode)
+}
+
+// StartQuestionPrefetcher remplit la réserve locale en tâche de fond, toutes les
+// OPENTDB_PREFETCH_INTERVAL (1h par défaut), jusqu'à OPENTDB_POOL_TARGET questions par catégorie.
+// OPENTDB_PREFETCH=false le désactive.
+func StartQuestionPrefetcher(ctx context.Context) {
+	if !config.Bool("OPENTDB_PREFETCH", true) {
+		return
+	}
+	interval := config.Duration("OPENTDB_PREFETCH_INTERVAL", time.Hour)
+	go func() {
+		for {
+			prefetchQuestions(ctx)
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// nombre de questions demandées par appel de préchargement, le maximum accepté par Open Trivia DB
+const prefetchAmount = 50
+
+// effectue un passage de remplissage sur toutes les catégories
+func prefetchQuestions(ctx context.Context) {
+	target := config.Int("OPENTDB_POOL_TARGET", 200)
+	// Open Trivia DB n'accepte qu'une requête toutes les 5 secondes par adresse IP
+	pause := config.Duration("OPENTDB_RATE_LIMIT_DELAY", 5*time.Second)
+
+	// un token propre au préchargement évite de recevoir les mêmes questions d'un appel à l'autre
+	token, err := requestOpenTDBToken(ctx)
+	if err != nil {
+		log.Printf("Préchargement des questions : token Open Trivia DB indisponible (%v)", err)
+		if openTDBUnavailable(err) {
+			return
+		}
+	}
+
+	total := 0
+	for _, category := range externalCategories(ctx, false) {
+		name := category.Name
+		count, err := store.CountPoolQuestions(ctx, name, "", "")
+		if err != nil {
+			log.Printf("Préchargement des questions : %v", err)
+			return
+		}
+		if count >= target {
+			continue
+		}
+
+		// Open Trivia DB ne renvoie rien (code 1) s'il reste moins de questions que demandé pour
+		// le token : la quantité est divisée par deux jusqu'à obtenir une réponse
+		amount := prefetchAmount
+		if category.Counts != nil && category.Counts.Total < amount {
+			amount = category.Counts.Total
+		}
+		var response openTDBResponse
+		for amount > 0 {
+			select {
+			case <-time.After(pause):
+			case <-ctx.Done():
+				return
+			}
+
+			params := url.Values{"amount": {strconv.Itoa(amount)}, "category": {strconv.Itoa(category.ID)}}
+			if token != "" {
+				params.Set("token", token)
+			}
+			response = openTDBResponse{}
+			if err = openTDBGet(ctx, "/api.php", params, &response); err != nil {
+				log.Printf("Préchargement des questions (%s) : %v", name, err)
+				if IsOpenTDBError(err, OpenTDBNetwork) || IsOpenTDBError(err, OpenTDBTimeout) {
+					return
+				}
+				break
+			}
+			if response.ResponseCode != openTDBNoResults {
+				break
+			}
+			amount /= 2
+		}
+		if err != nil || response.ResponseCode != openTDBSuccess {
+			// erreur, ou catégorie épuisée pour ce token
+			continue
+		}
+
+		fetched := parseOpenTDBResults(name, response.Results)
+		added, err := store.AddPoolQuestions(ctx, fetched)
+		if err != nil {
+			log.Printf("Préchargement des questions (%s) : %v", name, err)
+			continue
+		}
+		questions := make([]model.Question, len(fetched))
+		for i, question := range fetched {
+			questions[i] = question.Question
+		}
+		queueExplanations(questions)
+		total += added
+	}
+	log.Printf("Préchargement des questions terminé : %d question(s) ajoutée(s) à la réserve", total)
+}
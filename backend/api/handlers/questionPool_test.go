@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"quizmaster/model"
+	"testing"
+	"time"
+)
+
+// ajoute n questions à la réserve de la catégorie General Knowledge
+func fillPool(t *testing.T, n int) {
+	t.Helper()
+	questions := make([]model.PoolQuestion, n)
+	for i := range questions {
+		questions[i] = model.PoolQuestion{
+			Category:   "General Knowledge",
+			Difficulty: model.DifficultyEasy,
+			Type:       model.TypeMultiple,
+			Question: model.Question{
+				QuestionText:    fmt.Sprintf("Question de la réserve %d", i),
+				Responses:       []string{"A", "B", "C", "D"},
+				ResponseCorrect: "A",
+			},
+			FetchedAt: time.Now(),
+		}
+	}
+	if _, err := store.AddPoolQuestions(context.Background(), questions); err != nil {
+		t.Fatalf("AddPoolQuestions : %v", err)
+	}
+}
+
+func TestGenerateQuizFromPool(t *testing.T) {
+	memory := useMemoryStore(t)
+	fake := startFakeOpenTDB(t)
+	useCategories(t, []ExternalCategory{{ID: 9, Name: "General Knowledge"}})
+	fillPool(t, 10)
+
+	ctx := context.Background()
+	user := model.User{Username: "alice", OpenTDBToken: "token-test"}
+	options := QuizOptions{Amount: 5, Difficulty: model.DifficultyEasy, Type: model.TypeMultiple, Scoring: model.ScoringClassic}
+	seen := map[string]bool{}
+
+	// la réserve contient deux quiz de questions inédites, puis Open Trivia DB la complète
+	for i, wantCalls := range []int{0, 0, 1} {
+		quiz, err := GenerateQuiz(ctx, user, "General Knowledge", options)
+		if err != nil {
+			t.Fatalf("quiz %d : %v", i+1, err)
+		}
+		if len(quiz.Questions) != options.Amount {
+			t.Fatalf("quiz %d : %d questions, attendu %d", i+1, len(quiz.Questions), options.Amount)
+		}
+		for _, question := range quiz.Questions {
+			if seen[question.QuestionText] {
+				t.Errorf("quiz %d : question déjà vue %q", i+1, question.QuestionText)
+			}
+			seen[question.QuestionText] = true
+		}
+		if calls := fake.count("/api.php"); calls != wantCalls {
+			t.Errorf("quiz %d : %d appel(s) à Open Trivia DB, attendu %d", i+1, calls, wantCalls)
+		}
+		if _, err = memory.CreateQuiz(ctx, quiz); err != nil {
+			t.Fatalf("CreateQuiz : %v", err)
+		}
+	}
+
+	if count, _ := memory.CountPoolQuestions(ctx, "General Knowledge", "", ""); count != 15 {
+		t.Errorf("%d questions dans la réserve, attendu 15", count)
+	}
+}
+
+func TestGenerateQuizPoolFallback(t *testing.T) {
+	memory := useMemoryStore(t)
+	fake := startFakeOpenTDB(t)
+	useCategories(t, []ExternalCategory{{ID: 9, Name: "General Knowledge"}})
+	fake.setDown(true)
+	fillPool(t, 3)
+
+	ctx := context.Background()
+	user := model.User{Username: "alice", OpenTDBToken: "token-test"}
+	options := QuizOptions{Amount: 5, Difficulty: model.DifficultyEasy, Type: model.TypeMultiple}
+
+	// la réserve ne contient pas assez de questions : pas de quiz raccourci
+	_, err := GenerateQuiz(ctx, user, "General Knowledge", options)
+	if !IsOpenTDBError(err, OpenTDBEmpty) {
+		t.Fatalf("erreur %v, attendu une réserve insuffisante", err)
+	}
+	rec := httptest.NewRecorder()
+	writeOpenTDBError(rec, err)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("statut %d, attendu %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// avec assez de questions, le quiz est servi sans Open Trivia DB
+	fillPool(t, 5)
+	quiz, err := GenerateQuiz(ctx, user, "General Knowledge", options)
+	if err != nil || len(quiz.Questions) != options.Amount {
+		t.Fatalf("quiz tiré de la réserve : %d questions (%v)", len(quiz.Questions), err)
+	}
+	if count, _ := memory.CountPoolQuestions(ctx, "General Knowledge", "", ""); count != 5 {
+		t.Errorf("%d questions dans la réserve, attendu 5", count)
+	}
+}
+
+func TestPrefetchQuestions(t *testing.T) {
+	memory := useMemoryStore(t)
+	fake := startFakeOpenTDB(t)
+	useCategories(t, []ExternalCategory{{ID: 9, Name: "General Knowledge"}, {ID: 10, Name: "Books"}})
+	t.Setenv("OPENTDB_POOL_TARGET", "60")
+	fillPool(t, 60)
+
+	ctx := context.Background()
+	prefetchQuestions(ctx)
+	// seule la catégorie en dessous de l'objectif est complétée
+	if calls := fake.count("/api.php"); calls != 1 {
+		t.Errorf("%d appel(s) à Open Trivia DB, attendu 1", calls)
+	}
+	if count, _ := memory.CountPoolQuestions(ctx, "Books", "", ""); count != prefetchAmount {
+		t.Errorf("%d questions Books dans la réserve, attendu %d", count, prefetchAmount)
+	}
+
+	// Open Trivia DB injoignable : le préchargement s'arrête sans erreur
+	fake.setDown(true)
+	prefetchQuestions(ctx)
+	if count, _ := memory.CountPoolQuestions(ctx, "Books", "", ""); count != prefetchAmount {
+		t.Errorf("%d questions Books dans la réserve, attendu %d", count, prefetchAmount)
+	}
+}
@@ -6,12 +6,44 @@ import (
 	"log"
 	"math/rand"
 	"quizmaster/db"
+	"strings"
 	"time"
 
 	"net/http"
 	"quizmaster/model"
 )
 
+// AnswerResult est le résultat d'une réponse, renvoyé par VerifyAnswer
+type AnswerResult struct {
+	CorrectAnswer string `json:"correctAnswer"`
+	// toutes les bonnes réponses (plusieurs pour une question à choix multiples)
+	CorrectAnswers []string `json:"correctAnswers"`
+	Correct        bool     `json:"correct"`
+	// explication et source de la question, quand elles existent
+	Explanation string `json:"explanation,omitempty"`
+	Source      string `json:"source,omitempty"`
+	// note de la réponse entre 0 et 1, intermédiaire pour une réponse partiellement juste
+	Credit       float64 `json:"credit"`
+	Late         bool    `json:"late"`
+	Skipped      bool    `json:"skipped"`
+	Mark         int     `json:"mark"`
+	Finished     bool    `json:"finished"`
+	NextQuestion int     `json:"nextQuestion"`
+	// points de la réponse selon la règle du quiz et total du quiz
+	Points      model.PointsBreakdown `json:"points"`
+	TotalPoints int                   `json:"totalPoints"`
+	// question suivante, absente quand le quiz est terminé
+	Next *QuestionView `json:"next,omitempty"`
+	// temps restant pour la question suivante, absent sans limite de temps
+	RemainingTime *int `json:"remainingTime,omitempty"`
+}
+
+// longueur maximale de l'en-tête Idempotency-Key
+const maxIdempotencyKeyLength = 100
+
+// VerifyAnswer enregistre la réponse à la question en cours du quiz. Chaque question ne reçoit
+// qu'une réponse : une requête rejouée avec le même en-tête Idempotency-Key renvoie le résultat
+// déjà enregistré, une réponse à une autre question que la question en cours est refusée (409).
 func VerifyAnswer(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
@@ -22,6 +54,10 @@ func VerifyAnswer(w http.ResponseWriter, r *http.Request) {
 	var requestData struct {
 		QuizID string `json:"quizID"`
 		Answer string `json:"answer"`
+		// réponses choisies d'une question à choix multiples
+		Answers []string `json:"answers"`
+		// numéro de la question à laquelle le joueur répond, optionnel
+		Question *int `json:"question"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
@@ -29,71 +65,172 @@ func VerifyAnswer(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Données invalides"})
 		return
 	}
+	requestID := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if len(requestID) > maxIdempotencyKeyLength {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Clé d'idempotence trop longue"})
+		return
+	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
+	user, ok := requireUser(w, r, "")
+	if !ok {
+		return
+	}
 
-	quiz, err := db.GetQuizByID(client, requestData.QuizID)
+	quiz, err := store.GetQuizByID(r.Context(), requestData.QuizID)
+	if err == db.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusNotFound, Message: "Quiz introuvable"})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la récupération du quiz"})
 		return
 	}
+	if !requireQuizOwner(w, user, quiz) {
+		return
+	}
+	if replayAnswer(w, r, quiz, requestID) {
+		return
+	}
+	if quiz.Finish || quiz.Number_question >= len(quiz.Questions) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Le quiz est déjà terminé"})
+		return
+	}
+	if requestData.Question != nil && *requestData.Question != quiz.Number_question {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Cette question n'est pas la question en cours", Data: currentQuestionView(quiz)})
+		return
+	}
 
-	var response string = quiz.Questions[quiz.Number_question].ResponseCorrect
-
-	if quiz.Questions[quiz.Number_question].ResponseCorrect == requestData.Answer {
-		quiz.Mark += 1
+	now := time.Now()
+	index := quiz.Number_question
+	question := quiz.Questions[index]
+	ensureAnswerRecords(&quiz)
+	record := &quiz.Answers[index]
+	record.AnsweredAt = &now
+	record.Answer = requestData.Answer
+	record.Answers = requestData.Answers
+	record.RequestID = requestID
+
+	// une réponse arrivée après la limite de temps ne rapporte pas de point
+	points := model.PointsBreakdown{}
+	if answerIsLate(quiz, now) {
+		record.Late = true
+		record.Skipped = lateAnswersSkipped()
+	} else {
+		record.Credit = gradeAnswer(question, requestData.Answer, requestData.Answers)
+		// sans heure d'envoi (quiz créé avant le chronomètre) il n'y a pas de bonus de rapidité
+		elapsed := time.Duration(-1)
+		if !record.ServedAt.IsZero() {
+			elapsed = now.Sub(record.ServedAt)
+		}
+		points = scorerFor(quiz).ScoreAnswer(quiz, record.Credit, elapsed, currentStreak(quiz))
+		if record.Credit >= 1 {
+			quiz.Mark += 1
+			record.Correct = true
+		}
 	}
+	record.Points = &points
+	quiz.Points += points.Total
 
 	quiz.Number_question++
 	if quiz.Number_question == len(quiz.Questions) {
 		quiz.Finish = true
-		AddStats(quiz.Username, quiz)
+		quiz.Status = model.QuizFinished
+		quiz.FinishedAt = &now
+	} else {
+		serveQuestion(&quiz, now)
 	}
 
 	log.Printf("Mise à jour du quiz avec l'ID : %s\n", quiz.ID)
-	_, err = db.UpdateQuiz(client, quiz)
+	err = store.UpdateQuiz(r.Context(), quiz)
+	if err == db.ErrConflict {
+		// une autre requête a répondu entre-temps : si c'était la même (requête renvoyée
+		// par le client), on renvoie son résultat
+		if latest, err := store.GetQuizByID(r.Context(), quiz.ID); err == nil && replayAnswer(w, r, latest, requestID) {
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "La question a déjà reçu une réponse"})
+		return
+	}
+	if err == db.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusNotFound, Message: "Quiz introuvable"})
+		return
+	}
 	if err != nil {
 		log.Printf("Erreur lors de la mise à jour du quiz : %v\n", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la mise à jour du quiz"})
 		return
 	}
+	// les récompenses ne sont versées que par la requête qui a terminé le quiz.
+	// En cas d'échec, la réconciliation les créditera plus tard.
+	if quiz.Finish {
+		if err = applyQuizRewards(r.Context(), quiz); err != nil {
+			log.Printf("Erreur lors du crédit des récompenses du quiz %s : %v\n", quiz.ID, err)
+		}
+	}
 
+	result := newAnswerResult(r.Context(), quiz, index, now)
 	responseMessage := "Réponse vérifiée avec succès"
+	if result.Late {
+		responseMessage = "Temps écoulé, la réponse n'est pas comptée"
+	}
 	if quiz.Finish {
 		responseMessage += " et le quiz est terminé"
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: responseMessage, Data: response})
+	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: responseMessage, Data: result})
 }
 
-func AddStats(userName string, quiz model.Quiz) {
-	client := db.Connect()
-	defer client.Disconnect(context.Background())
-
-	user, err := db.GetUserByName(client, userName)
-	if err != nil {
-		log.Printf("Erreur lors de la récupération de l'utilisateur : %v\n", err)
-		return
+// renvoie le résultat déjà enregistré de la réponse envoyée avec la clé requestID.
+// Retourne false si aucune réponse du quiz n'a cette clé.
+func replayAnswer(w http.ResponseWriter, r *http.Request, quiz model.Quiz, requestID string) bool {
+	if requestID == "" {
+		return false
 	}
-
-	user.Coins += 100 + 10*quiz.Mark
-	user.Experience += 10 + quiz.Mark
-	user.Stats.PlayedQuizzes += 1
-	user.Stats.CorrectResponses += quiz.Mark
-	if quiz.Mark == quiz.Number_question {
-		user.Stats.FullMarks += 1
+	for i, record := range quiz.Answers {
+		if record.RequestID != requestID || record.AnsweredAt == nil {
+			continue
+		}
+		log.Printf("Réponse %d du quiz %s rejouée", i, quiz.ID)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Réponse déjà enregistrée", Data: newAnswerResult(r.Context(), quiz, i, time.Now())})
+		return true
 	}
+	return false
+}
 
-	_, err = db.UpdateUser(client, user)
-	if err != nil {
-		log.Printf("Erreur lors de la mise à jour de l'utilisateur : %v\n", err)
-		return
+// résultat de la réponse enregistrée à la question index, avec l'état actuel du quiz
+func newAnswerResult(ctx context.Context, quiz model.Quiz, index int, now time.Time) AnswerResult {
+	question := quiz.Questions[index]
+	record := quiz.Answers[index]
+	result := AnswerResult{
+		CorrectAnswers: correctAnswers(question),
+		Correct:        record.Correct,
+		Explanation:    questionExplanation(ctx, question),
+		Source:         question.Source,
+		Credit:         record.Credit,
+		Late:           record.Late,
+		Skipped:        record.Skipped,
+		Mark:           quiz.Mark,
+		Finished:       quiz.Finish,
+		NextQuestion:   quiz.Number_question,
+		TotalPoints:    quiz.Points,
+		Next:           currentQuestionView(quiz),
+		RemainingTime:  remainingTime(quiz, now),
 	}
-
+	result.CorrectAnswer = strings.Join(result.CorrectAnswers, ", ")
+	if record.Points != nil {
+		result.Points = *record.Points
+	}
+	return result
 }
 
 func CreateQuestionHandler(w http.ResponseWriter, r *http.Request) {
@@ -115,14 +252,36 @@ func CreateQuestionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.Background())
+	user, ok := requireUser(w, r, QuestionData.Username)
+	if !ok {
+		return
+	}
+	QuestionData.Username = user.Username
+
+	if err := validateQuestion(&QuestionData.Question); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
 
 	// Log des données reçues
 	log.Printf("Données reçues - Catégorie: %s, Question: %s", QuestionData.CategoryName, QuestionData.Question.QuestionText)
 
+	// Seul le créateur d'une catégorie existante peut y ajouter des questions
+	category, err := store.GetCategoryByName(r.Context(), QuestionData.CategoryName)
+	if err != nil && err != db.ErrNotFound {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la vérification de la catégorie"})
+		return
+	}
+	if err == nil && category.Username != user.Username {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusForbidden, Message: "Cette catégorie appartient à un autre utilisateur"})
+		return
+	}
+
 	// Vérification de l'existence de la question
-	existQuestion, question, err := db.ExistQuestion(client, QuestionData.Username, QuestionData.CategoryName, QuestionData.Question)
+	existQuestion, question, err := store.ExistQuestion(r.Context(), QuestionData.Username, QuestionData.CategoryName, QuestionData.Question)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la vérification de l'existence de la question"})
@@ -138,7 +297,7 @@ func CreateQuestionHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Création de la question
-	err = db.CreateQuestion(client, QuestionData.Username, QuestionData.CategoryName, QuestionData.Question)
+	err = store.CreateQuestion(r.Context(), QuestionData.Username, QuestionData.CategoryName, QuestionData.Question)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la création de la question"})
@@ -179,6 +338,8 @@ func CreateQuizHandler(w http.ResponseWriter, r *http.Request) {
 	var QuizData struct {
 		Username     string `json:"username"`
 		CategoryName string `json:"categoryname"`
+		TimeLimit    int    `json:"timeLimit"`
+		Scoring      string `json:"scoring"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&QuizData); err != nil {
@@ -186,18 +347,32 @@ func CreateQuizHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Données invalides"})
 		return
 	}
+	if err := validateTimeLimit(QuizData.TimeLimit); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	scoring, err := parseScoring(QuizData.Scoring)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.Background())
+	user, ok := requireUser(w, r, QuizData.Username)
+	if !ok {
+		return
+	}
+	QuizData.Username = user.Username
 
-	boolexist, onGoingQuiz := db.OnGoingQuiz(client, QuizData.Username)
+	boolexist, onGoingQuiz := store.OnGoingQuiz(r.Context(), QuizData.Username, model.QuizModeCustom, QuizData.CategoryName)
 	if boolexist {
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz récupéré avec succès", Data: onGoingQuiz})
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz récupéré avec succès", Data: newQuizView(onGoingQuiz)})
 		return
 	}
 
-	allquestions := db.GetQuestionsByCategory(client, QuizData.CategoryName)
+	allquestions := store.GetQuestionsByCategory(r.Context(), QuizData.CategoryName)
 	if len(allquestions) < 10 {
 		log.Printf("Nombre de questions insuffisant: %d", len(allquestions))
 		w.WriteHeader(http.StatusBadRequest)
@@ -213,9 +388,18 @@ func CreateQuizHandler(w http.ResponseWriter, r *http.Request) {
 		Mark:            0,
 		Finish:          false,
 		Number_question: 0,
+		Status:          model.QuizInProgress,
+		Mode:            model.QuizModeCustom,
+		Category:        QuizData.CategoryName,
+		Amount:          10,
+		TimeLimit:       QuizData.TimeLimit,
+		Scoring:         scoring,
+		CreatedAt:       time.Now(),
 	}
+	// le chronomètre de la première question démarre à l'envoi du quiz
+	serveQuestion(&quiz, time.Now())
 
-	quiz, err := db.CreateQuiz(client, quiz)
+	quiz, err = store.CreateQuiz(r.Context(), quiz)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la création du quiz"})
@@ -225,5 +409,5 @@ func CreateQuizHandler(w http.ResponseWriter, r *http.Request) {
 	// Quiz créé avec succès
 	log.Printf("Quiz créé avec succès: %v", quiz)
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz créé avec succès", Data: quiz})
+	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Quiz créé avec succès", Data: newQuizView(quiz)})
 }
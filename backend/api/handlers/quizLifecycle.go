@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"quizmaster/config"
+	"quizmaster/db"
+	"quizmaster/model"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// état du quiz, déduit de Finish pour les quiz créés avant l'enregistrement de l'état
+func quizStatus(quiz model.Quiz) string {
+	if quiz.Status != "" {
+		return quiz.Status
+	}
+	if quiz.Finish {
+		return model.QuizFinished
+	}
+	return model.QuizInProgress
+}
+
+// récupère le quiz {quizid} de l'utilisateur connecté, répond une erreur et retourne false sinon
+func ownedQuiz(w http.ResponseWriter, r *http.Request) (model.Quiz, bool) {
+	user, ok := requireUser(w, r, "")
+	if !ok {
+		return model.Quiz{}, false
+	}
+	quiz, err := store.GetQuizByID(r.Context(), mux.Vars(r)["quizid"])
+	if err == db.ErrNotFound {
+		writeJSON(w, http.StatusNotFound, "Quiz introuvable", nil)
+		return quiz, false
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération du quiz", nil)
+		return quiz, false
+	}
+	if !requireQuizOwner(w, user, quiz) {
+		return quiz, false
+	}
+	return quiz, true
+}
+
+// OnGoingQuizzesHandler liste les quiz en cours de l'utilisateur, à reprendre ou abandonner
+func OnGoingQuizzesHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireUser(w, r, "")
+	if !ok {
+		return
+	}
+	quizzes, err := store.ListOnGoingQuizzes(r.Context(), user.Username)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération des quiz en cours", nil)
+		return
+	}
+
+	views := make([]QuizView, len(quizzes))
+	for i, quiz := range quizzes {
+		views[i] = newQuizView(quiz)
+	}
+	writeJSON(w, http.StatusOK, "Quiz en cours récupérés avec succès", views)
+}
+
+// ResumeQuizHandler retourne un quiz en cours pour le reprendre à la question où il s'était arrêté.
+// Le chronomètre de la question en cours n'est pas remis à zéro.
+func ResumeQuizHandler(w http.ResponseWriter, r *http.Request) {
+	quiz, ok := ownedQuiz(w, r)
+	if !ok {
+		return
+	}
+	if quiz.Finish {
+		writeJSON(w, http.StatusConflict, "Le quiz est déjà terminé", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Quiz récupéré avec succès", newQuizView(quiz))
+}
+
+// AbandonQuizHandler abandonne un quiz en cours : il ne rapporte rien et est compté dans les statistiques
+func AbandonQuizHandler(w http.ResponseWriter, r *http.Request) {
+	quiz, ok := ownedQuiz(w, r)
+	if !ok {
+		return
+	}
+
+	err := store.CloseQuiz(r.Context(), quiz.ID, model.QuizAbandoned, time.Now())
+	if err == db.ErrNoChange {
+		writeJSON(w, http.StatusConflict, "Le quiz est déjà terminé", nil)
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de l'abandon du quiz", nil)
+		return
+	}
+	if err = store.AddAbandonedQuiz(r.Context(), quiz.Username); err != nil {
+		log.Printf("Erreur lors de la mise à jour des statistiques de %s : %v", quiz.Username, err)
+	}
+
+	log.Printf("Quiz %s abandonné par %s", quiz.ID, quiz.Username)
+	writeJSON(w, http.StatusOK, "Quiz abandonné", nil)
+}
+
+// StartQuizExpiry fait expirer en tâche de fond, toutes les QUIZ_EXPIRY_INTERVAL (10 min par défaut),
+// les quiz en cours sans activité depuis QUIZ_EXPIRY (24h par défaut, 0 pour désactiver).
+func StartQuizExpiry(ctx context.Context) {
+	expiry := config.Duration("QUIZ_EXPIRY", 24*time.Hour)
+	if expiry <= 0 {
+		return
+	}
+	interval := config.Duration("QUIZ_EXPIRY_INTERVAL", 10*time.Minute)
+	go func() {
+		for {
+			expireStaleQuizzes(ctx, expiry)
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// termine les quiz délaissés, par lots de 100, et les compte comme abandonnés
+func expireStaleQuizzes(ctx context.Context, expiry time.Duration) {
+	total := 0
+	for ctx.Err() == nil {
+		now := time.Now()
+		quizzes, err := store.ListStaleQuizzes(ctx, now.Add(-expiry), 100)
+		if err != nil {
+			log.Printf("Expiration des quiz : %v", err)
+			return
+		}
+
+		expired := 0
+		for _, quiz := range quizzes {
+			err = store.CloseQuiz(ctx, quiz.ID, model.QuizExpired, now)
+			if err == db.ErrNoChange {
+				continue
+			}
+			if err != nil {
+				log.Printf("Expiration du quiz %s : %v", quiz.ID, err)
+				continue
+			}
+			expired++
+			if err = store.AddAbandonedQuiz(ctx, quiz.Username); err != nil {
+				log.Printf("Erreur lors de la mise à jour des statistiques de %s : %v", quiz.Username, err)
+			}
+		}
+		total += expired
+		// lot incomplet ou aucun quiz fermé (erreurs) : on reprendra au prochain passage
+		if len(quizzes) < 100 || expired == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Expiration des quiz : %d quiz expiré(s)", total)
+	}
+}
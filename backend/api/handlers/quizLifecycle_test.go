@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"quizmaster/model"
+	"testing"
+	"time"
+)
+
+func TestExpireStaleQuizzes(t *testing.T) {
+	memory := useMemoryStore(t)
+	ctx := context.Background()
+	userID, err := memory.InsertUser(ctx, model.User{Username: "alice"})
+	if err != nil {
+		t.Fatalf("InsertUser : %v", err)
+	}
+	create := func(quiz model.Quiz) string {
+		quiz.Username = "alice"
+		created, err := memory.CreateQuiz(ctx, quiz)
+		if err != nil {
+			t.Fatalf("CreateQuiz : %v", err)
+		}
+		return created.ID
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	// plus d'un lot de quiz délaissés
+	var stale []string
+	for i := 0; i < 150; i++ {
+		stale = append(stale, create(model.Quiz{Status: model.QuizInProgress, UpdatedAt: old}))
+	}
+	active := create(model.Quiz{Status: model.QuizInProgress})
+	finished := create(model.Quiz{Status: model.QuizFinished, Finish: true, UpdatedAt: old})
+
+	expireStaleQuizzes(ctx, time.Hour)
+
+	for _, id := range stale {
+		if quiz, _ := memory.GetQuizByID(ctx, id); !quiz.Finish || quiz.Status != model.QuizExpired {
+			t.Fatalf("quiz délaissé %s : %+v", id, quiz)
+		}
+	}
+	if quiz, _ := memory.GetQuizByID(ctx, active); quiz.Finish {
+		t.Errorf("quiz actif expiré : %+v", quiz)
+	}
+	if quiz, _ := memory.GetQuizByID(ctx, finished); quiz.Status != model.QuizFinished {
+		t.Errorf("quiz terminé modifié : %+v", quiz)
+	}
+	if user, _ := memory.GetUserByID(ctx, userID); user.Stats.AbandonedQuizzes != len(stale) {
+		t.Errorf("%d quiz abandonnés dans les statistiques, attendu %d", user.Stats.AbandonedQuizzes, len(stale))
+	}
+
+	// un second passage ne compte pas deux fois les mêmes quiz
+	expireStaleQuizzes(ctx, time.Hour)
+	if user, _ := memory.GetUserByID(ctx, userID); user.Stats.AbandonedQuizzes != len(stale) {
+		t.Errorf("%d quiz abandonnés après un second passage, attendu %d", user.Stats.AbandonedQuizzes, len(stale))
+	}
+}
+
+func TestQuizStatus(t *testing.T) {
+	tests := []struct {
+		quiz model.Quiz
+		want string
+	}{
+		{model.Quiz{}, model.QuizInProgress},
+		{model.Quiz{Finish: true}, model.QuizFinished},
+		{model.Quiz{Finish: true, Status: model.QuizAbandoned}, model.QuizAbandoned},
+	}
+	for _, tt := range tests {
+		if status := quizStatus(tt.quiz); status != tt.want {
+			t.Errorf("quizStatus(%+v) = %s, attendu %s", tt.quiz, status, tt.want)
+		}
+	}
+}
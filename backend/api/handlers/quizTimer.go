@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"quizmaster/config"
+	"quizmaster/model"
+	"time"
+)
+
+// bornes de la limite de temps par question, en secondes
+const (
+	minTimeLimit = 5
+	maxTimeLimit = 300
+)
+
+// vérifie une limite de temps par question : 0 (pas de limite) ou entre 5 et 300 secondes
+func validateTimeLimit(seconds int) error {
+	if seconds != 0 && (seconds < minTimeLimit || seconds > maxTimeLimit) {
+		return fmt.Errorf("La limite de temps doit être comprise entre %d et %d secondes", minTimeLimit, maxTimeLimit)
+	}
+	return nil
+}
+
+// crée le suivi des questions jusqu'à la question en cours
+// (les quiz créés avant le chronomètre n'en ont pas)
+func ensureAnswerRecords(quiz *model.Quiz) {
+	for len(quiz.Answers) <= quiz.Number_question {
+		quiz.Answers = append(quiz.Answers, model.AnswerRecord{})
+	}
+}
+
+// marque la question en cours comme servie à l'instant now
+func serveQuestion(quiz *model.Quiz, now time.Time) {
+	ensureAnswerRecords(quiz)
+	quiz.Answers[quiz.Number_question].ServedAt = now
+}
+
+// retourne l'heure limite de réponse à la question en cours, false s'il n'y en a pas
+func questionDeadline(quiz model.Quiz) (time.Time, bool) {
+	if quiz.TimeLimit <= 0 || quiz.Number_question >= len(quiz.Answers) {
+		return time.Time{}, false
+	}
+	served := quiz.Answers[quiz.Number_question].ServedAt
+	if served.IsZero() {
+		return time.Time{}, false
+	}
+	return served.Add(time.Duration(quiz.TimeLimit) * time.Second), true
+}
+
+// indique si une réponse reçue à now dépasse la limite de temps.
+// QUIZ_TIME_GRACE (2s par défaut) compense le délai du réseau.
+func answerIsLate(quiz model.Quiz, now time.Time) bool {
+	deadline, ok := questionDeadline(quiz)
+	if !ok {
+		return false
+	}
+	return now.After(deadline.Add(config.Duration("QUIZ_TIME_GRACE", 2*time.Second)))
+}
+
+// une réponse en retard est comptée fausse ("wrong", par défaut) ou passée ("skip") selon QUIZ_LATE_ANSWERS
+func lateAnswersSkipped() bool {
+	return config.String("QUIZ_LATE_ANSWERS", "wrong") == "skip"
+}
+
+// retourne le temps restant en secondes pour la question en cours, nil sans limite
+func remainingTime(quiz model.Quiz, now time.Time) *int {
+	if quiz.Finish {
+		return nil
+	}
+	deadline, ok := questionDeadline(quiz)
+	if !ok {
+		return nil
+	}
+	seconds := int(math.Ceil(deadline.Sub(now).Seconds()))
+	if seconds < 0 {
+		seconds = 0
+	}
+	return &seconds
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"quizmaster/model"
+	"testing"
+	"time"
+)
+
+func TestValidateTimeLimit(t *testing.T) {
+	for seconds, valid := range map[int]bool{0: true, 5: true, 300: true, 4: false, 301: false, -1: false} {
+		if err := validateTimeLimit(seconds); (err == nil) != valid {
+			t.Errorf("validateTimeLimit(%d) = %v", seconds, err)
+		}
+	}
+}
+
+func TestQuestionTimer(t *testing.T) {
+	served := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	timed := model.Quiz{TimeLimit: 10, Answers: []model.AnswerRecord{{ServedAt: served}}}
+	seconds := func(n int) *int { return &n }
+
+	tests := []struct {
+		name          string
+		quiz          model.Quiz
+		now           time.Time
+		wantLate      bool
+		wantRemaining *int
+	}{
+		{"sans limite de temps", model.Quiz{Answers: []model.AnswerRecord{{ServedAt: served}}}, served.Add(time.Hour), false, nil},
+		{"question non servie", model.Quiz{TimeLimit: 10}, served.Add(time.Hour), false, nil},
+		{"dans les temps", timed, served.Add(3500 * time.Millisecond), false, seconds(7)},
+		{"dans le délai de grâce", timed, served.Add(11 * time.Second), false, seconds(0)},
+		{"en retard", timed, served.Add(13 * time.Second), true, seconds(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if late := answerIsLate(tt.quiz, tt.now); late != tt.wantLate {
+				t.Errorf("answerIsLate = %v, attendu %v", late, tt.wantLate)
+			}
+			remaining := remainingTime(tt.quiz, tt.now)
+			if (remaining == nil) != (tt.wantRemaining == nil) || (remaining != nil && *remaining != *tt.wantRemaining) {
+				t.Errorf("remainingTime = %v, attendu %v", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestServeQuestion(t *testing.T) {
+	now := time.Now()
+	// quiz créé avant le chronomètre, sans suivi des questions
+	quiz := model.Quiz{TimeLimit: 10, Number_question: 2}
+	serveQuestion(&quiz, now)
+	if len(quiz.Answers) != 3 || !quiz.Answers[2].ServedAt.Equal(now) {
+		t.Fatalf("suivi des questions : %+v", quiz.Answers)
+	}
+	if deadline, ok := questionDeadline(quiz); !ok || !deadline.Equal(now.Add(10*time.Second)) {
+		t.Errorf("heure limite %v, %v", deadline, ok)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"quizmaster/db"
+	"quizmaster/model"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QuizView est le quiz envoyé au joueur pendant la partie : seule la question en cours
+// est visible, sans sa bonne réponse
+type QuizView struct {
+	ID              string `json:"ID"`
+	Username        string
+	Mark            int
+	Finish          bool
+	Status          string
+	Mode            string
+	Category        string
+	Number_question int
+	// nombre total de questions
+	Amount     int
+	Difficulty string
+	Type       string
+	TimeLimit  int
+	Scoring    string
+	Points     int
+	// question en cours, absente quand le quiz est terminé
+	Question *QuestionView `json:",omitempty"`
+	// temps restant pour la question en cours, absent sans limite de temps
+	RemainingTime *int `json:",omitempty"`
+}
+
+// QuestionView est une question sans ses bonnes réponses, ses réponses mélangées
+type QuestionView struct {
+	Type         string   `json:"type"`
+	QuestionText string   `json:"question_text"`
+	Responses    []string `json:"responses"`
+}
+
+// QuizSummary résume un quiz terminé dans l'historique du joueur
+type QuizSummary struct {
+	ID         string     `json:"ID"`
+	Status     string     `json:"status"`
+	Mode       string     `json:"mode,omitempty"`
+	Category   string     `json:"category"`
+	Difficulty string     `json:"difficulty,omitempty"`
+	Scoring    string     `json:"scoring"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// durée du quiz en secondes, absente pour les quiz terminés avant l'enregistrement de la date de fin
+	Duration        *int `json:"duration,omitempty"`
+	Mark            int  `json:"mark"`
+	Amount          int  `json:"amount"`
+	Points          int  `json:"points"`
+	CheatSheetsUsed int  `json:"cheatSheetsUsed"`
+}
+
+// QuizReview est la correction d'un quiz terminé
+type QuizReview struct {
+	QuizSummary
+	Questions []QuestionReview `json:"questions"`
+}
+
+// QuestionReview est une question corrigée avec la réponse du joueur
+type QuestionReview struct {
+	// position de la question dans le quiz, à partir de 0
+	Number         int                 `json:"number"`
+	Type           string              `json:"type"`
+	QuestionText   string              `json:"question_text"`
+	Responses      []string            `json:"responses"`
+	CorrectAnswers []string            `json:"correctAnswers"`
+	Explanation    string              `json:"explanation,omitempty"`
+	Source         string              `json:"source,omitempty"`
+	Answer         *model.AnswerRecord `json:"answer,omitempty"`
+}
+
+// construit la vue du quiz à envoyer au joueur
+func newQuizView(quiz model.Quiz) QuizView {
+	view := QuizView{
+		ID:              quiz.ID,
+		Username:        quiz.Username,
+		Mark:            quiz.Mark,
+		Finish:          quiz.Finish,
+		Status:          quizStatus(quiz),
+		Mode:            quiz.Mode,
+		Category:        quiz.Category,
+		Number_question: quiz.Number_question,
+		Amount:          len(quiz.Questions),
+		Difficulty:      quiz.Difficulty,
+		Type:            quiz.Type,
+		TimeLimit:       quiz.TimeLimit,
+		Scoring:         scorerName(quiz),
+		Points:          quiz.Points,
+		Question:        currentQuestionView(quiz),
+		RemainingTime:   remainingTime(quiz, time.Now()),
+	}
+	return view
+}
+
+// question en cours du quiz, nil si le quiz est terminé
+func currentQuestionView(quiz model.Quiz) *QuestionView {
+	if quiz.Finish || quiz.Number_question >= len(quiz.Questions) {
+		return nil
+	}
+	question := quiz.Questions[quiz.Number_question]
+	return &QuestionView{
+		Type:         questionType(question),
+		QuestionText: question.QuestionText,
+		Responses:    question.Responses,
+	}
+}
+
+// résumé d'un quiz terminé
+func newQuizSummary(quiz model.Quiz) QuizSummary {
+	summary := QuizSummary{
+		ID:              quiz.ID,
+		Status:          quizStatus(quiz),
+		Mode:            quiz.Mode,
+		Category:        quiz.Category,
+		Difficulty:      quiz.Difficulty,
+		Scoring:         scorerName(quiz),
+		CreatedAt:       quizCreatedAt(quiz),
+		FinishedAt:      quiz.FinishedAt,
+		Mark:            quiz.Mark,
+		Amount:          len(quiz.Questions),
+		Points:          quiz.Points,
+		CheatSheetsUsed: quiz.CheatSheetsUsed,
+	}
+	if quiz.FinishedAt != nil && !summary.CreatedAt.IsZero() {
+		seconds := int(quiz.FinishedAt.Sub(summary.CreatedAt).Seconds())
+		summary.Duration = &seconds
+	}
+	return summary
+}
+
+// date de création du quiz. Les quiz créés avant son enregistrement
+// utilisent la date contenue dans leur ObjectID.
+func quizCreatedAt(quiz model.Quiz) time.Time {
+	if !quiz.CreatedAt.IsZero() {
+		return quiz.CreatedAt
+	}
+	if id, err := primitive.ObjectIDFromHex(quiz.ID); err == nil {
+		return id.Timestamp()
+	}
+	return time.Time{}
+}
+
+// nom de la règle de calcul des points du quiz
+func scorerName(quiz model.Quiz) string {
+	if _, ok := scorers[quiz.Scoring]; ok {
+		return quiz.Scoring
+	}
+	return model.ScoringClassic
+}
+
+// ReviewQuizHandler retourne la correction d'un quiz terminé à son joueur
+func ReviewQuizHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireUser(w, r, "")
+	if !ok {
+		return
+	}
+
+	quiz, err := store.GetQuizByID(r.Context(), mux.Vars(r)["quizid"])
+	if err == db.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusNotFound, Message: "Quiz introuvable"})
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la récupération du quiz"})
+		return
+	}
+	if !requireQuizOwner(w, user, quiz) {
+		return
+	}
+	// les bonnes réponses ne sont révélées qu'une fois le quiz terminé
+	if !quiz.Finish {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusConflict, Message: "Le quiz n'est pas terminé"})
+		return
+	}
+
+	// un quiz abandonné, expiré ou fermé par un modérateur ne révèle que les questions auxquelles
+	// le joueur a répondu : les autres peuvent resservir dans un prochain quiz
+	completed := quizStatus(quiz) == model.QuizFinished
+	review := QuizReview{QuizSummary: newQuizSummary(quiz), Questions: []QuestionReview{}}
+	for i, question := range quiz.Questions {
+		answered := i < len(quiz.Answers) && quiz.Answers[i].AnsweredAt != nil
+		if !completed && !answered {
+			continue
+		}
+		item := QuestionReview{
+			Number:         i,
+			Type:           questionType(question),
+			QuestionText:   question.QuestionText,
+			Responses:      question.Responses,
+			CorrectAnswers: correctAnswers(question),
+			Explanation:    questionExplanation(r.Context(), question),
+			Source:         question.Source,
+		}
+		if i < len(quiz.Answers) {
+			item.Answer = &quiz.Answers[i]
+		}
+		review.Questions = append(review.Questions, item)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Correction du quiz", Data: review})
+}
+
+// QuizHistoryHandler retourne l'historique paginé des quiz terminés de l'utilisateur (?page=1&limit=20)
+func QuizHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireSelf(w, r)
+	if !ok {
+		return
+	}
+
+	_, limit, skip := pagination(r)
+	quizzes, err := store.ListUserQuizzes(r.Context(), user.Username, skip, limit)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération de l'historique", nil)
+		return
+	}
+
+	history := make([]QuizSummary, len(quizzes))
+	for i, quiz := range quizzes {
+		history[i] = newQuizSummary(quiz)
+	}
+	writeJSON(w, http.StatusOK, "Historique récupéré avec succès", history)
+}
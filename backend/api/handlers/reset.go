@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"quizmaster/auth"
+	"quizmaster/config"
+	"quizmaster/db"
+	"quizmaster/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// limite les demandes de réinitialisation par nom d'utilisateur
+// (PASSWORD_RESET_LIMIT demandes par PASSWORD_RESET_WINDOW, 3 par heure par défaut)
+var (
+	resetLimiterOnce sync.Once
+	resetLimiter     *auth.RateLimiter
+)
+
+func passwordResetLimiter() *auth.RateLimiter {
+	resetLimiterOnce.Do(func() {
+		resetLimiter = auth.NewRateLimiter(
+			config.Int("PASSWORD_RESET_LIMIT", 3),
+			config.Duration("PASSWORD_RESET_WINDOW", time.Hour),
+		)
+	})
+	return resetLimiter
+}
+
+// RequestPasswordResetHandler envoie un lien de réinitialisation à l'adresse e-mail du compte.
+// La réponse est identique que le compte existe ou non, pour ne pas révéler les noms d'utilisateurs.
+func RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Réception d'une requête POST sur /requestPasswordReset")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+	if mailer == nil {
+		writeJSON(w, http.StatusServiceUnavailable, "La réinitialisation du mot de passe est désactivée", nil)
+		return
+	}
+
+	var requestData struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Username == "" {
+		writeJSON(w, http.StatusBadRequest, "Nom d'utilisateur manquant", nil)
+		return
+	}
+
+	if !passwordResetLimiter().Allow(strings.ToLower(requestData.Username)) {
+		writeJSON(w, http.StatusTooManyRequests, "Trop de demandes de réinitialisation, réessayez plus tard", nil)
+		return
+	}
+
+	const message = "Si ce compte existe et possède une adresse e-mail, un lien de réinitialisation a été envoyé"
+	user, err := store.GetUserByName(r.Context(), requestData.Username)
+	if err == db.ErrNotFound || (err == nil && (user.Email == "" || user.Banned)) {
+		writeJSON(w, http.StatusOK, message, nil)
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur lors de la récupération de l'utilisateur : %v", err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	token, expiresAt, err := db.NewPasswordReset(r.Context(), store, user.ID)
+	if err != nil {
+		log.Printf("Erreur lors de la création de la demande de réinitialisation : %v", err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	// l'envoi ne bloque pas la réponse, un échec est seulement journalisé
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Quiz Master ++ : réinitialisation du mot de passe",
+		Body: fmt.Sprintf("Bonjour %s,\n\nPour choisir un nouveau mot de passe, ouvrez le lien suivant avant le %s :\n%s%s\n\n"+
+			"Si vous n'êtes pas à l'origine de cette demande, ignorez ce message.\n",
+			user.Username, expiresAt.Format("02/01/2006 15:04"),
+			config.String("PASSWORD_RESET_URL", "http://localhost:5173/quizmaster/reset-password?token="), token),
+		Secrets: []string{token},
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.Duration("MAIL_TIMEOUT", 30*time.Second))
+		defer cancel()
+		if err := mailer.Send(ctx, msg); err != nil {
+			log.Printf("Erreur lors de l'envoi de l'e-mail de réinitialisation à %s : %v", user.Username, err)
+		}
+	}()
+
+	writeJSON(w, http.StatusOK, message, nil)
+}
+
+// ResetPasswordHandler remplace le mot de passe à l'aide d'un token de réinitialisation
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Réception d'une requête POST sur /resetPassword")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Token == "" {
+		writeJSON(w, http.StatusBadRequest, "Données invalides", nil)
+		return
+	}
+
+	user, err := db.ResetPassword(r.Context(), store, requestData.Token, requestData.NewPassword)
+	if err == db.ErrInvalidToken || err == db.ErrExpiredToken {
+		writeJSON(w, http.StatusBadRequest, "Lien de réinitialisation invalide ou expiré", nil)
+		return
+	}
+	if auth.IsPolicyError(err) {
+		writeJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur lors de la réinitialisation du mot de passe : %v", err)
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Mot de passe réinitialisé pour %s", user.Username)
+	writeJSON(w, http.StatusOK, "Mot de passe réinitialisé, veuillez vous reconnecter", nil)
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"quizmaster/model"
+)
+
+// écrit une réponse JSON avec le statut donné
+func writeJSON(w http.ResponseWriter, status int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.ApiResponse{Status: status, Message: message, Data: data})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"quizmaster/config"
+	"quizmaster/db"
+	"quizmaster/model"
+	"time"
+)
+
+// récompenses d'un quiz terminé selon sa règle de calcul des points
+func quizReward(quiz model.Quiz) model.QuizReward {
+	coins, experience := scorerFor(quiz).Rewards(quiz)
+	return model.QuizReward{
+		Coins:            coins,
+		Experience:       experience,
+		CorrectResponses: quiz.Mark,
+		FullMark:         quiz.Mark == quiz.Number_question,
+	}
+}
+
+// crédite les récompenses d'un quiz terminé à son joueur puis marque le quiz comme récompensé.
+// Le crédit est fait une seule fois par quiz, la fonction peut donc être relancée sans risque.
+func applyQuizRewards(ctx context.Context, quiz model.Quiz) error {
+	err := store.CreditQuizRewards(ctx, quiz.Username, quiz.ID, quizReward(quiz))
+	switch err {
+	case nil:
+	case db.ErrNoChange:
+		log.Printf("Récompenses du quiz %s déjà créditées", quiz.ID)
+	case db.ErrNotFound:
+		log.Printf("Récompenses du quiz %s non créditées : utilisateur %s introuvable", quiz.ID, quiz.Username)
+	default:
+		return err
+	}
+	return store.MarkQuizRewarded(ctx, quiz.ID)
+}
+
+// StartRewardReconciler crédite en tâche de fond, toutes les REWARDS_RECONCILE_INTERVAL (5 min par
+// défaut, 0 pour désactiver), les récompenses des quiz terminés depuis plus de REWARDS_RECONCILE_DELAY
+// (1 min par défaut) qui n'ont pas pu l'être à la fin du quiz.
+func StartRewardReconciler(ctx context.Context) {
+	interval := config.Duration("REWARDS_RECONCILE_INTERVAL", 5*time.Minute)
+	if interval <= 0 {
+		return
+	}
+	delay := config.Duration("REWARDS_RECONCILE_DELAY", time.Minute)
+	go func() {
+		for {
+			reconcileRewards(ctx, delay)
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// crédite les récompenses manquantes, par lots de 100
+func reconcileRewards(ctx context.Context, delay time.Duration) {
+	total := 0
+	for ctx.Err() == nil {
+		quizzes, err := store.ListUnrewardedQuizzes(ctx, time.Now().Add(-delay), 100)
+		if err != nil {
+			log.Printf("Réconciliation des récompenses : %v", err)
+			return
+		}
+
+		applied := 0
+		for _, quiz := range quizzes {
+			if err = applyQuizRewards(ctx, quiz); err != nil {
+				log.Printf("Réconciliation des récompenses du quiz %s : %v", quiz.ID, err)
+				continue
+			}
+			applied++
+		}
+		total += applied
+		// lot incomplet ou aucun quiz traité (erreurs) : on reprendra au prochain passage
+		if len(quizzes) < 100 || applied == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Réconciliation des récompenses : %d quiz récompensé(s)", total)
+	}
+}
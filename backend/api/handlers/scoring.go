@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"quizmaster/config"
+	"quizmaster/model"
+	"time"
+)
+
+// Scorer est une règle de calcul des points d'un quiz
+type Scorer interface {
+	// ScoreAnswer calcule les points de la réponse à la question en cours.
+	// credit est la note de la réponse (1 si juste, 0 si fausse, entre les deux si partiellement juste),
+	// elapsed le temps de réponse, streak le nombre de bonnes réponses consécutives avant celle-ci.
+	ScoreAnswer(quiz model.Quiz, credit float64, elapsed time.Duration, streak int) model.PointsBreakdown
+	// Rewards retourne les pièces et l'expérience gagnées pour le quiz terminé
+	Rewards(quiz model.Quiz) (int, int)
+}
+
+// règles disponibles, choisies à la création du quiz
+var scorers = map[string]Scorer{
+	model.ScoringClassic:     classicScorer{},
+	model.ScoringCompetitive: competitiveScorer{},
+}
+
+// retourne la règle du quiz, la règle classique pour les quiz qui n'en ont pas
+func scorerFor(quiz model.Quiz) Scorer {
+	if scorer, ok := scorers[quiz.Scoring]; ok {
+		return scorer
+	}
+	return scorers[model.ScoringClassic]
+}
+
+// vérifie le nom d'une règle de calcul, vide pour la règle classique
+func parseScoring(scoring string) (string, error) {
+	if scoring == "" {
+		return model.ScoringClassic, nil
+	}
+	if _, ok := scorers[scoring]; !ok {
+		return "", fmt.Errorf("Règle de calcul des points invalide (classic ou competitive)")
+	}
+	return scoring, nil
+}
+
+// multiplicateur des récompenses selon la difficulté du quiz.
+// Les quiz des catégories créées par les joueurs n'ont pas de difficulté et valent comme "easy".
+var difficultyMultipliers = map[string]float64{
+	model.DifficultyEasy:   1,
+	model.DifficultyAny:    1.25,
+	model.DifficultyMedium: 1.5,
+	model.DifficultyHard:   2,
+}
+
+func difficultyMultiplier(difficulty string) float64 {
+	if multiplier, ok := difficultyMultipliers[difficulty]; ok {
+		return multiplier
+	}
+	return 1
+}
+
+// ================== Règle classique ==================
+
+// points d'une bonne réponse avec la règle classique
+const classicBase = 10
+
+// classicScorer : 10 points par bonne réponse, une part pour une réponse partiellement juste
+type classicScorer struct{}
+
+func (classicScorer) ScoreAnswer(quiz model.Quiz, credit float64, elapsed time.Duration, streak int) model.PointsBreakdown {
+	base := int(math.Round(classicBase * credit))
+	return model.PointsBreakdown{Base: base, Total: base}
+}
+
+// La part fixe est proportionnelle au nombre de questions (100 pièces et 10 XP pour 10 questions),
+// chaque bonne réponse rapporte 10 pièces et 1 XP.
+func (classicScorer) Rewards(quiz model.Quiz) (int, int) {
+	multiplier := difficultyMultiplier(quiz.Difficulty)
+	questions := len(quiz.Questions)
+	coins := float64(10*questions+quiz.Points) * multiplier
+	experience := (float64(questions) + float64(quiz.Points)/classicBase) * multiplier
+	return int(math.Round(coins)), int(math.Round(experience))
+}
+
+// ================== Règle compétitive ==================
+
+// points de la règle compétitive
+const (
+	competitiveBase      = 100 // bonne réponse
+	competitiveMaxSpeed  = 50  // réponse immédiate, décroît jusqu'à 0 à la fin de la fenêtre de temps
+	competitiveStreak    = 10  // par bonne réponse consécutive précédente
+	competitiveMaxStreak = 50
+)
+
+// competitiveScorer récompense la rapidité et les séries de bonnes réponses
+type competitiveScorer struct{}
+
+// fenêtre du bonus de rapidité : la limite de temps du quiz, sinon QUIZ_SPEED_WINDOW (20s par défaut)
+func speedWindow(quiz model.Quiz) time.Duration {
+	if quiz.TimeLimit > 0 {
+		return time.Duration(quiz.TimeLimit) * time.Second
+	}
+	return config.Duration("QUIZ_SPEED_WINDOW", 20*time.Second)
+}
+
+// Une réponse partiellement juste rapporte une part des points de base et du bonus de rapidité,
+// seule une réponse juste prolonge la série.
+func (competitiveScorer) ScoreAnswer(quiz model.Quiz, credit float64, elapsed time.Duration, streak int) model.PointsBreakdown {
+	if credit <= 0 {
+		return model.PointsBreakdown{}
+	}
+	points := model.PointsBreakdown{Base: int(math.Round(competitiveBase * credit))}
+
+	if window := speedWindow(quiz); elapsed >= 0 && elapsed < window {
+		points.SpeedBonus = int(math.Round(competitiveMaxSpeed * credit * (1 - float64(elapsed)/float64(window))))
+	}
+	if credit >= 1 {
+		points.StreakBonus = competitiveStreak * streak
+		if points.StreakBonus > competitiveMaxStreak {
+			points.StreakBonus = competitiveMaxStreak
+		}
+	}
+
+	points.Total = points.Base + points.SpeedBonus + points.StreakBonus
+	return points
+}
+
+// Les pièces et l'expérience suivent les points : 10 questions parfaites rapportent environ 285 pièces.
+func (competitiveScorer) Rewards(quiz model.Quiz) (int, int) {
+	multiplier := difficultyMultiplier(quiz.Difficulty)
+	questions := len(quiz.Questions)
+	coins := float64(10*questions+quiz.Points/10) * multiplier
+	experience := float64(questions+quiz.Points/50) * multiplier
+	return int(math.Round(coins)), int(math.Round(experience))
+}
+
+// nombre de bonnes réponses consécutives avant la question en cours
+func currentStreak(quiz model.Quiz) int {
+	streak := 0
+	for i := quiz.Number_question - 1; i >= 0 && i < len(quiz.Answers); i-- {
+		if !quiz.Answers[i].Correct {
+			break
+		}
+		streak++
+	}
+	return streak
+}
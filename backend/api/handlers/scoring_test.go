@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"quizmaster/model"
+	"testing"
+	"time"
+)
+
+func TestClassicScorer(t *testing.T) {
+	scorer := classicScorer{}
+	for credit, want := range map[float64]int{1: 10, 0.5: 5, 0: 0} {
+		// le temps de réponse et la série ne comptent pas
+		if points := scorer.ScoreAnswer(model.Quiz{}, credit, time.Second, 5); points.Total != want || points.Base != want {
+			t.Errorf("note %v : %+v, attendu %d points", credit, points, want)
+		}
+	}
+}
+
+func TestCompetitiveScorer(t *testing.T) {
+	scorer := competitiveScorer{}
+	timed := model.Quiz{TimeLimit: 10}
+
+	tests := []struct {
+		name    string
+		quiz    model.Quiz
+		credit  float64
+		elapsed time.Duration
+		streak  int
+		want    model.PointsBreakdown
+	}{
+		{"réponse fausse", timed, 0, 0, 3, model.PointsBreakdown{}},
+		{"réponse immédiate", timed, 1, 0, 0, model.PointsBreakdown{Base: 100, SpeedBonus: 50, Total: 150}},
+		{"à mi-temps", timed, 1, 5 * time.Second, 0, model.PointsBreakdown{Base: 100, SpeedBonus: 25, Total: 125}},
+		{"après la fenêtre", timed, 1, 10 * time.Second, 0, model.PointsBreakdown{Base: 100, Total: 100}},
+		{"sans heure d'envoi", timed, 1, -1, 0, model.PointsBreakdown{Base: 100, Total: 100}},
+		{"série de deux", timed, 1, 10 * time.Second, 2, model.PointsBreakdown{Base: 100, StreakBonus: 20, Total: 120}},
+		{"série plafonnée", timed, 1, 10 * time.Second, 9, model.PointsBreakdown{Base: 100, StreakBonus: 50, Total: 150}},
+		// une réponse partiellement juste ne prolonge pas la série
+		{"réponse partielle", timed, 0.5, 0, 4, model.PointsBreakdown{Base: 50, SpeedBonus: 25, Total: 75}},
+		{"fenêtre par défaut", model.Quiz{}, 1, 10 * time.Second, 0, model.PointsBreakdown{Base: 100, SpeedBonus: 25, Total: 125}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if points := scorer.ScoreAnswer(tt.quiz, tt.credit, tt.elapsed, tt.streak); points != tt.want {
+				t.Errorf("points %+v, attendu %+v", points, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewards(t *testing.T) {
+	questions := make([]model.Question, 10)
+	tests := []struct {
+		name           string
+		quiz           model.Quiz
+		wantCoins      int
+		wantExperience int
+	}{
+		{"classique sans bonne réponse", model.Quiz{Questions: questions}, 100, 10},
+		{"classique parfait", model.Quiz{Questions: questions, Points: 100}, 200, 20},
+		{"classique difficile", model.Quiz{Questions: questions, Points: 100, Difficulty: model.DifficultyHard}, 400, 40},
+		{"catégorie de joueur", model.Quiz{Questions: questions, Points: 50, Difficulty: ""}, 150, 15},
+		{"compétitif parfait", model.Quiz{Questions: questions, Points: 1850, Scoring: model.ScoringCompetitive}, 285, 47},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coins, experience := scorerFor(tt.quiz).Rewards(tt.quiz)
+			if coins != tt.wantCoins || experience != tt.wantExperience {
+				t.Errorf("%d pièces et %d XP, attendu %d et %d", coins, experience, tt.wantCoins, tt.wantExperience)
+			}
+		})
+	}
+}
+
+func TestCurrentStreak(t *testing.T) {
+	answers := []model.AnswerRecord{{Correct: true}, {Correct: false}, {Correct: true}, {Correct: true}, {}}
+	for question, want := range map[int]int{0: 0, 1: 1, 2: 0, 4: 2} {
+		if streak := currentStreak(model.Quiz{Number_question: question, Answers: answers}); streak != want {
+			t.Errorf("question %d : série de %d, attendu %d", question, streak, want)
+		}
+	}
+}
+
+func TestParseScoring(t *testing.T) {
+	for scoring, want := range map[string]string{"": model.ScoringClassic, model.ScoringCompetitive: model.ScoringCompetitive} {
+		if got, err := parseScoring(scoring); err != nil || got != want {
+			t.Errorf("parseScoring(%q) = %q, %v", scoring, got, err)
+		}
+	}
+	if _, err := parseScoring("inconnue"); err == nil {
+		t.Error("règle inconnue acceptée")
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"quizmaster/db"
+	"quizmaster/model"
+)
+
+// RefreshHandler échange un token de rafraîchissement contre une nouvelle paire de tokens
+func RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Réception d'une requête POST sur /refresh")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.RefreshToken == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(db.LoginResponse{Status: http.StatusBadRequest, Message: "Token de rafraîchissement manquant"})
+		return
+	}
+
+	response, err := db.RefreshSession(r.Context(), store, requestData.RefreshToken)
+	if err == db.ErrInvalidToken || err == db.ErrExpiredToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(db.LoginResponse{Status: http.StatusUnauthorized, Message: "Session invalide ou expirée"})
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur lors du renouvellement de la session : %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(db.LoginResponse{Status: http.StatusInternalServerError, Message: "Erreur serveur"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// LogoutAllHandler révoque toutes les sessions de l'utilisateur ("se déconnecter partout")
+func LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Réception d'une requête POST sur /logoutAll")
+	if r.Method != http.MethodPost {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := CurrentUser(r)
+	if !ok {
+		http.Error(w, "Authentification requise", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := store.DeleteUserSessions(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "Erreur lors de la déconnexion", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("%d session(s) révoquée(s) pour %s", count, user.Username)
+	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Déconnecté de toutes les sessions", Data: count})
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"quizmaster/db"
+	"quizmaster/mail"
+)
+
+// store est le stockage partagé par tous les handlers, initialisé au démarrage
+var store db.Store
+
+// mailer envoie les e-mails (réinitialisation de mot de passe), nil si aucun transport n'est configuré
+var mailer mail.Mailer
+
+// SetStore définit le stockage utilisé par les handlers
+func SetStore(s db.Store) {
+	store = s
+}
+
+// SetMailer définit le transport d'e-mails utilisé par les handlers
+func SetMailer(m mail.Mailer) {
+	mailer = m
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"quizmaster/auth"
+	"quizmaster/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compteurs d'échecs de connexion, par nom d'utilisateur et par adresse IP.
+// Ils sont créés au premier usage pour tenir compte de la configuration chargée depuis .env.
+var (
+	loginThrottleOnce sync.Once
+	userThrottle      *auth.Throttle
+	ipThrottle        *auth.Throttle
+)
+
+func loginThrottles() (*auth.Throttle, *auth.Throttle) {
+	loginThrottleOnce.Do(func() {
+		baseDelay := config.Duration("LOGIN_BASE_DELAY", time.Second)
+		maxDelay := config.Duration("LOGIN_MAX_DELAY", 5*time.Minute)
+		lockout := config.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+		window := config.Duration("LOGIN_FAILURE_WINDOW", time.Hour)
+
+		userThrottle = auth.NewThrottle(auth.ThrottleConfig{
+			FreeAttempts:    config.Int("LOGIN_FREE_ATTEMPTS", 3),
+			BaseDelay:       baseDelay,
+			MaxDelay:        maxDelay,
+			MaxFailures:     config.Int("LOGIN_MAX_FAILURES", 10),
+			LockoutDuration: lockout,
+			ResetAfter:      window,
+		})
+		// une adresse IP peut être partagée par plusieurs joueurs, les seuils sont plus élevés
+		ipThrottle = auth.NewThrottle(auth.ThrottleConfig{
+			FreeAttempts:    config.Int("LOGIN_IP_FREE_ATTEMPTS", 10),
+			BaseDelay:       baseDelay,
+			MaxDelay:        maxDelay,
+			MaxFailures:     config.Int("LOGIN_IP_MAX_FAILURES", 100),
+			LockoutDuration: lockout,
+			ResetAfter:      window,
+		})
+	})
+	return userThrottle, ipThrottle
+}
+
+// clé de comptage pour un nom d'utilisateur, insensible à la casse
+func usernameKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// clientIP retourne l'adresse du client. X-Forwarded-For n'est pris en compte
+// que derrière un proxy de confiance (TRUST_PROXY=true), sinon il serait falsifiable.
+func clientIP(r *http.Request) string {
+	if config.Bool("TRUST_PROXY", false) {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -1,19 +1,20 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	netmail "net/mail"
+	"quizmaster/auth"
 	"quizmaster/db"
 	"quizmaster/model"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var profileNames = []string{
@@ -39,6 +40,14 @@ func getRandomProfile() string {
 	return profileNames[r.Intn(len(profileNames))]
 }
 
+// identifiants envoyés à la création de compte et à la connexion. Le mot de passe n'est pas lu
+// dans model.User, qui ne le sérialise jamais en JSON.
+type credentialsRequest struct {
+	Username string
+	Password string
+	Email    string
+}
+
 func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Réception d'une requête POST sur /createUser")
 	if r.Method != http.MethodPost {
@@ -47,18 +56,16 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var newUser model.User
-	if err := json.NewDecoder(r.Body).Decode(&newUser); err != nil {
+	var requestData credentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Données invalides"})
 		return
 	}
-
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
+	newUser := model.User{Username: requestData.Username, Email: requestData.Email}
 
 	// Vérifier si le nom d'utilisateur existe déjà
-	exists, err := db.UsernameExists(client, newUser.Username)
+	exists, err := store.UsernameExists(r.Context(), newUser.Username)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la vérification du nom d'utilisateur"})
@@ -70,14 +77,28 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Hachage du mot de passe
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
+	// L'adresse e-mail est facultative, elle sert à la réinitialisation du mot de passe
+	if newUser.Email != "" {
+		if newUser.Email, err = normalizeEmail(newUser.Email); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Adresse e-mail invalide"})
+			return
+		}
+	}
+
+	// Vérification de la politique de mots de passe puis hachage
+	if err = auth.ValidatePassword(requestData.Password, newUser.Username); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	hashedPassword, err := auth.HashPassword(requestData.Password)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors du hachage du mot de passe"})
 		return
 	}
-	newUser.Password = string(hashedPassword)
+	newUser.Password = hashedPassword
 
 	// Initialisation des valeurs par défaut
 	newUser.Experience = 0
@@ -90,9 +111,14 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	newUser.Stats = model.Stats{PlayedQuizzes: 0, CorrectResponses: 0, FullMarks: 0, UsedCheatSheets: 0}
 	newUser.Picture = "/src/assets/profils/" + getRandomProfile() + ".png"
+	newUser.Role = model.RolePlayer
+	if db.IsAdminUsername(newUser.Username) {
+		newUser.Role = model.RoleAdmin
+	}
+	newUser.Banned = false
 
 	// Insertion en base
-	_, err = db.InsertUser(client, newUser)
+	_, err = store.InsertUser(r.Context(), newUser)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de l'insertion de l'utilisateur"})
@@ -114,10 +140,7 @@ func GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-
-	users, err := db.GetAllUsers(client)
+	users, err := store.GetAllUsers(r.Context())
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des utilisateurs", http.StatusInternalServerError)
 		return
@@ -139,14 +162,13 @@ func GetUserByNameHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Nom d'utilisateur manquant", http.StatusBadRequest)
 		return
 	}
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-
-	user, err := db.GetUserByName(client, username)
+	user, err := store.GetUserByName(r.Context(), username)
 	if err != nil {
 		http.Error(w, "Utilisateur non trouvé", http.StatusNotFound)
 		return
 	}
+	// profil public : l'adresse e-mail n'est visible que par son propriétaire
+	user.Email = ""
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
@@ -159,20 +181,12 @@ func GetUserTokenHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
 		return
 	}
-	token := r.Header.Get("Authorization")
-	if token == "" {
+	// l'utilisateur a déjà été résolu à partir du token par le middleware d'authentification
+	user, ok := CurrentUser(r)
+	if !ok {
 		http.Error(w, "Token manquant", http.StatusUnauthorized)
 		return
 	}
-
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-	log.Println(token)
-	user, err := db.GetUserByToken(client, token)
-	if err != nil {
-		http.Error(w, "Utilisateur pas trouvé", http.StatusNotFound)
-		return
-	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(user)
 }
@@ -185,32 +199,61 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var credentials model.User
+	var credentials credentialsRequest
 	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(db.LoginResponse{Status: http.StatusBadRequest, Message: "Erreur lors du décodage des identifiants"})
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
+	// Ralentissement des tentatives répétées, par nom d'utilisateur et par adresse IP.
+	// Check réserve la tentative, elle doit ensuite être soldée par Failure, Success ou Release.
+	users, ips := loginThrottles()
+	userKey, ip := usernameKey(credentials.Username), clientIP(r)
+	wait, allowed := users.Check(userKey)
+	if allowed {
+		if wait, allowed = ips.Check(ip); !allowed {
+			users.Release(userKey)
+		}
+	}
+	if !allowed {
+		seconds := int(wait.Seconds()) + 1
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(db.LoginResponse{Status: http.StatusTooManyRequests, Message: fmt.Sprintf("Trop de tentatives, réessayez dans %d secondes", seconds)})
+		return
+	}
 
-	log.Printf("Recherche de l'utilisateur avec le nom d'utilisateur: %s et le mot de passe: %s\n", credentials.Username, credentials.Password)
+	log.Printf("Tentative de connexion de l'utilisateur %s depuis %s\n", credentials.Username, ip)
 	// Vérifier les identifiants de l'utilisateur et obtenir le token
-	loginResponse, err := db.Login(client, credentials.Username, credentials.Password)
+	loginResponse, err := db.Login(r.Context(), store, credentials.Username, credentials.Password)
 	if err != nil {
+		users.Release(userKey)
+		ips.Release(ip)
 		log.Printf("Erreur serveur: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(db.LoginResponse{Status: http.StatusInternalServerError, Message: "Erreur serveur"})
 		return
 	}
 
+	if loginResponse.Status == http.StatusUnauthorized {
+		status := users.Failure(userKey)
+		ips.Failure(ip)
+		if status.Locked {
+			log.Printf("Compte %s verrouillé après %d échecs de connexion\n", credentials.Username, status.Failures)
+		}
+	} else {
+		// le mot de passe est correct (même pour un compte banni), les échecs de l'adresse IP sont conservés
+		users.Success(userKey)
+		ips.Release(ip)
+	}
+
 	// Utilisation de la réponse structurée pour déterminer le statut HTTP et le message
 	w.WriteHeader(loginResponse.Status)
 	json.NewEncoder(w).Encode(loginResponse)
 }
 
-// LogoutHandler gère la déconnexion d'un utilisateur
+// LogoutHandler gère la déconnexion d'un utilisateur en révoquant la session courante
 func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Réception d'une requête POST sur /logout")
 	if r.Method != http.MethodPost {
@@ -218,13 +261,11 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	userID := vars["userid"]
-
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-	err := db.DeleteUserToken(client, userID)
-	if err != nil {
+	if _, ok := requireSelf(w, r); !ok {
+		return
+	}
+	session, _ := currentSession(r)
+	if err := store.DeleteSession(r.Context(), session.ID); err != nil {
 		http.Error(w, "Erreur lors de la déconnexion", http.StatusInternalServerError)
 		return
 	}
@@ -241,18 +282,23 @@ func DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	userID := vars["userid"]
-
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
+	user, ok := requireSelf(w, r)
+	if !ok {
+		return
+	}
+	userID := user.ID
 
-	err := db.DeleteUser(client, userID)
+	err := store.DeleteUser(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Erreur lors de la suppression de l'utilisateur", http.StatusInternalServerError)
 		return
 	}
 
+	// Les sessions du compte supprimé ne doivent plus être utilisables
+	if _, err = store.DeleteUserSessions(r.Context(), userID); err != nil {
+		log.Printf("Erreur lors de la révocation des sessions : %v\n", err)
+	}
+
 	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Compte utilisateur supprimé avec succès"})
 }
 
@@ -264,8 +310,11 @@ func UpdateUserUsernameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	userID := vars["userid"]
+	user, ok := requireSelf(w, r)
+	if !ok {
+		return
+	}
+	userID := user.ID
 
 	var requestData struct {
 		NewUsername string `json:"newUsername"`
@@ -275,10 +324,7 @@ func UpdateUserUsernameHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-
-	err := db.UpdateUserUsername(client, userID, requestData.NewUsername)
+	err := store.UpdateUserUsername(r.Context(), userID, requestData.NewUsername)
 	if err != nil {
 		http.Error(w, "Erreur lors de la mise à jour du username", http.StatusInternalServerError)
 		return
@@ -295,8 +341,11 @@ func UpdateUserPictureHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	userID := vars["userid"]
+	user, ok := requireSelf(w, r)
+	if !ok {
+		return
+	}
+	userID := user.ID
 
 	var requestData struct {
 		NewPicture string `json:"newPicture"`
@@ -306,10 +355,7 @@ func UpdateUserPictureHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-
-	err := db.SetUserPicture(client, userID, requestData.NewPicture)
+	err := store.SetUserPicture(r.Context(), userID, requestData.NewPicture)
 	if err != nil {
 		log.Printf("Erreur mise à jour de l'image: %v\n", err)
 		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
@@ -331,28 +377,114 @@ func UpdateUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	userID := vars["userid"]
+	user, ok := requireSelf(w, r)
+	if !ok {
+		return
+	}
 	var requestData struct {
-		NewPassword string `json:"newPassword"`
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
 		http.Error(w, "Données invalides", http.StatusBadRequest)
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
+	// Le mot de passe actuel est exigé même avec une session valide
+	if !auth.CheckPassword(user.Password, requestData.CurrentPassword) {
+		writeJSON(w, http.StatusForbidden, "Mot de passe actuel incorrect", nil)
+		return
+	}
+	if err := auth.ValidatePassword(requestData.NewPassword, user.Username); err != nil {
+		writeJSON(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if requestData.NewPassword == requestData.CurrentPassword {
+		writeJSON(w, http.StatusBadRequest, "Le nouveau mot de passe doit être différent de l'actuel", nil)
+		return
+	}
 
-	err := db.UpdateUserPassword(client, userID, requestData.NewPassword)
+	hashedPassword, err := auth.HashPassword(requestData.NewPassword)
+	if err != nil {
+		http.Error(w, "Erreur lors du hachage du mot de passe", http.StatusInternalServerError)
+		return
+	}
+	err = store.UpdateUserPassword(r.Context(), user.ID, hashedPassword)
 	if err != nil {
 		http.Error(w, "Erreur lors de la mise à jour du mot de passe", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusOK, Message: "Mot de passe mis à jour avec succès"})
+	// Les autres sessions sont révoquées, seule la session courante reste ouverte
+	session, _ := currentSession(r)
+	count, err := store.DeleteOtherSessions(r.Context(), user.ID, session.ID)
+	if err != nil {
+		log.Printf("Erreur lors de la révocation des sessions : %v\n", err)
+	}
+
+	writeJSON(w, http.StatusOK, "Mot de passe mis à jour avec succès", map[string]int{"revokedSessions": count})
+}
+
+// normalise et valide une adresse e-mail
+func normalizeEmail(email string) (string, error) {
+	address, err := netmail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(address.Address), nil
 }
 
+// UpdateUserEmailHandler change l'adresse e-mail utilisée pour la réinitialisation du mot de passe
+func UpdateUserEmailHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Réception d'une requête PUT sur /changeEmail")
+	if r.Method != http.MethodPut {
+		http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, ok := requireSelf(w, r)
+	if !ok {
+		return
+	}
+	var requestData struct {
+		CurrentPassword string `json:"currentPassword"`
+		Email           string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Données invalides", http.StatusBadRequest)
+		return
+	}
+
+	// l'adresse e-mail permet de reprendre le compte, le mot de passe actuel est exigé
+	if !auth.CheckPassword(user.Password, requestData.CurrentPassword) {
+		writeJSON(w, http.StatusForbidden, "Mot de passe actuel incorrect", nil)
+		return
+	}
+	email := ""
+	if requestData.Email != "" {
+		var err error
+		if email, err = normalizeEmail(requestData.Email); err != nil {
+			writeJSON(w, http.StatusBadRequest, "Adresse e-mail invalide", nil)
+			return
+		}
+	}
+
+	if err := store.SetUserEmail(r.Context(), user.ID, email); err != nil {
+		http.Error(w, "Erreur lors de la mise à jour de l'adresse e-mail", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, "Adresse e-mail mise à jour avec succès", nil)
+}
+
+// CategorySummary décrit une catégorie créée par un joueur sans ses questions,
+// dont les bonnes réponses ne doivent pas être publiques
+type CategorySummary struct {
+	Username      string `json:"Username"`
+	CategoryName  string `json:"CategoryName"`
+	QuestionCount int    `json:"QuestionCount"`
+}
+
+// GetUserCategoriesHandler liste les catégories visibles (d'un utilisateur si username est donné), sans leurs questions
 func GetUserCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Réception d'une requête GET sur /getUserCategories")
 
@@ -364,26 +496,60 @@ func GetUserCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	// Extraire l'username des paramètres de requête
 	username := r.URL.Query().Get("username")
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-
 	// Récupérer les catégories de l'utilisateur
-	categories, err := db.GetUserCategories(client, username)
+	categories, err := store.GetUserCategories(r.Context(), username, false)
 	if err != nil {
 		log.Printf("Erreur lors de la récupération des catégories : %v", err)
 		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
 		return
 	}
 
+	summaries := make([]CategorySummary, 0, len(categories))
+	for _, category := range categories {
+		summaries = append(summaries, CategorySummary{
+			Username:      category.Username,
+			CategoryName:  category.CategoryName,
+			QuestionCount: len(category.Questions),
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(model.ApiResponse{
 		Status:  http.StatusOK,
 		Message: "Catégories récupérées avec succès",
-		Data:    categories,
+		Data:    summaries,
 	})
 }
 
+// GetOwnCategoryHandler renvoie une catégorie avec ses questions et leurs réponses,
+// uniquement à l'utilisateur qui l'a créée (pour la modifier)
+func GetOwnCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("Réception d'une requête GET sur /category")
+
+	user, ok := requireUser(w, r, "")
+	if !ok {
+		return
+	}
+
+	category, err := store.GetCategoryByName(r.Context(), mux.Vars(r)["category"])
+	if err == db.ErrNotFound {
+		writeJSON(w, http.StatusNotFound, "La catégorie n'existe pas", nil)
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur lors de la récupération de la catégorie : %v", err)
+		writeJSON(w, http.StatusInternalServerError, "Erreur lors de la récupération de la catégorie", nil)
+		return
+	}
+	if category.Username != user.Username {
+		writeJSON(w, http.StatusForbidden, "Cette catégorie appartient à un autre utilisateur", nil)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, "Catégorie récupérée avec succès", category)
+}
+
 type UserRanking struct {
 	Username   string `bson:"username"`
 	Experience int    `bson:"experience"`
@@ -393,23 +559,15 @@ type UserRanking struct {
 func GetTopPlayers(w http.ResponseWriter, r *http.Request) {
 	log.Println("Réception d'une requête GET sur /getTopPlayers")
 
-	client := db.Connect()
-	defer client.Disconnect(context.TODO())
-
-	// Sélection de la collection
-	collection := client.Database("DB").Collection("users")
-
-	cursor, err := collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.D{{"experience", -1}}).SetLimit(5))
+	players, err := store.GetTopPlayers(r.Context(), 5)
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des utilisateurs", http.StatusInternalServerError)
 		return
 	}
-	defer cursor.Close(context.TODO())
 
 	var users []UserRanking
-	if err = cursor.All(context.TODO(), &users); err != nil {
-		http.Error(w, "Erreur lors du décodage des utilisateurs", http.StatusInternalServerError)
-		return
+	for _, player := range players {
+		users = append(users, UserRanking{Username: player.Username, Experience: player.Experience, Picture: player.Picture})
 	}
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(users)
@@ -433,19 +591,26 @@ func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Données invalides"})
 		return
 	}
+	user, ok := requireUser(w, r, categoryData.Username)
+	if !ok {
+		return
+	}
+	categoryData.Username = user.Username
 	log.Printf("Données reçues - Catégorie: %s, Utilisateur: %s", categoryData.CategoryName, categoryData.Username)
 
-	if categoryData.Username == "" || categoryData.CategoryName == "" {
+	if categoryData.CategoryName == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Le nom de la catégorie et l'utilisateur sont requis"})
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Le nom de la catégorie est requis"})
+		return
+	}
+	if err := validateQuestions(categoryData.Questions); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: err.Error()})
 		return
 	}
-
-	client := db.Connect()
-	defer client.Disconnect(context.Background())
 
 	// Vérifier si la catégorie existe déjà pour cet utilisateur
-	exists, err := db.CategoryExists(client, categoryData.CategoryName)
+	exists, err := store.CategoryExists(r.Context(), categoryData.CategoryName)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la vérification de la catégorie"})
@@ -458,7 +623,7 @@ func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Créer la catégorie
-	err = db.CreateCategory(client, categoryData.Username, categoryData.CategoryName, categoryData.Questions)
+	err = store.CreateCategory(r.Context(), categoryData.Username, categoryData.CategoryName, categoryData.Questions)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la création de la catégorie"})
@@ -489,33 +654,45 @@ func UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Données invalides"})
 		return
 	}
+	user, ok := requireUser(w, r, categoryData.Username)
+	if !ok {
+		return
+	}
+	categoryData.Username = user.Username
 	log.Printf("Données reçues - Utilisateur: %s, Catégorie actuelle: %s, Nouveau nom: %s", categoryData.Username, categoryData.CategoryName, categoryData.NewCategoryName)
 
-	if categoryData.Username == "" || categoryData.CategoryName == "" {
+	if categoryData.CategoryName == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Le nom de l'utilisateur et le nom actuel de la catégorie sont requis"})
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: "Le nom actuel de la catégorie est requis"})
+		return
+	}
+	if err := validateQuestions(categoryData.Questions); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusBadRequest, Message: err.Error()})
 		return
 	}
 
-	client := db.Connect()
-	defer client.Disconnect(context.Background())
-
-	// Vérifier si la catégorie existe pour cet utilisateur
-	exists, err := db.CategoryExists(client, categoryData.CategoryName)
+	// Vérifier si la catégorie existe et appartient à l'utilisateur
+	category, err := store.GetCategoryByName(r.Context(), categoryData.CategoryName)
+	if err == db.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusNotFound, Message: "La catégorie n'existe pas"})
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la vérification de la catégorie"})
 		return
 	}
-	if !exists {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusNotFound, Message: "La catégorie n'existe pas"})
+	if category.Username != user.Username {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusForbidden, Message: "Cette catégorie appartient à un autre utilisateur"})
 		return
 	}
 
 	// Si newCategoryName est différent et non vide, vérifier qu'il n'existe pas déjà
 	if categoryData.NewCategoryName != "" && categoryData.NewCategoryName != categoryData.CategoryName {
-		exists, err = db.CategoryExists(client, categoryData.NewCategoryName)
+		exists, err := store.CategoryExists(r.Context(), categoryData.NewCategoryName)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la vérification du nouveau nom de catégorie"})
@@ -529,7 +706,7 @@ func UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Mettre à jour la catégorie
-	err = db.UpdateCategory(client, categoryData.Username, categoryData.CategoryName, categoryData.NewCategoryName, categoryData.Questions)
+	err = store.UpdateCategory(r.Context(), categoryData.Username, categoryData.CategoryName, categoryData.NewCategoryName, categoryData.Questions)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusInternalServerError, Message: "Erreur lors de la mise à jour de la catégorie"})
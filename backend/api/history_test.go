@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestQuizReviewAndHistory(t *testing.T) {
+	s := newTestServer(t)
+	token, userID := s.signup("alice")
+	bob, _ := s.signup("bob")
+	quizID := s.startQuiz(token, "alice")
+
+	// la correction n'est disponible qu'une fois le quiz terminé
+	if rec := s.do("GET", "/api/quiz/review/"+quizID, token, nil); rec.Code != http.StatusConflict {
+		t.Errorf("correction d'un quiz en cours : %d, attendu %d", rec.Code, http.StatusConflict)
+	}
+	for i := 0; i < 10; i++ {
+		answer := "A"
+		if i%2 == 1 {
+			answer = "B"
+		}
+		s.do("POST", "/api/quiz/verifyAnswer", token, map[string]interface{}{"quizID": quizID, "answer": answer})
+	}
+
+	var review struct {
+		Mark      int `json:"mark"`
+		Amount    int `json:"amount"`
+		Questions []struct {
+			CorrectAnswers []string `json:"correctAnswers"`
+			Answer         *struct {
+				Answer  string `json:"Answer"`
+				Correct bool   `json:"Correct"`
+			} `json:"answer"`
+		} `json:"questions"`
+	}
+	rec := s.do("GET", "/api/quiz/review/"+quizID, token, nil)
+	if decode(t, rec, &review); rec.Code != http.StatusOK || review.Mark != 5 || len(review.Questions) != 10 {
+		t.Fatalf("correction : %d %s", rec.Code, rec.Body.String())
+	}
+	for i, question := range review.Questions {
+		if len(question.CorrectAnswers) != 1 || question.CorrectAnswers[0] != "A" || question.Answer == nil || question.Answer.Correct != (i%2 == 0) {
+			t.Errorf("question %d corrigée : %+v", i, question)
+		}
+	}
+	if rec := s.do("GET", "/api/quiz/review/"+quizID, bob, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("correction du quiz d'un autre joueur : %d, attendu %d", rec.Code, http.StatusForbidden)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"première page", "", 1},
+		{"page suivante", "?page=2&limit=1", 0},
+		// une page énorme ne doit pas produire un décalage négatif
+		{"page énorme", "?page=9223372036854775807", 0},
+		{"page énorme et limite maximale", "?page=92233720368547758&limit=100", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var history []struct {
+				ID   string `json:"ID"`
+				Mark int    `json:"mark"`
+			}
+			rec := s.do("GET", "/api/user/quizHistory/"+userID+tt.query, token, nil)
+			if decode(t, rec, &history); rec.Code != http.StatusOK || len(history) != tt.want {
+				t.Fatalf("historique : %d %s, attendu %d quiz", rec.Code, rec.Body.String(), tt.want)
+			}
+			if tt.want > 0 && (history[0].ID != quizID || history[0].Mark != 5) {
+				t.Errorf("historique : %+v", history)
+			}
+		})
+	}
+}
+
+// les autres listes paginées acceptent aussi une page énorme
+func TestHugePage(t *testing.T) {
+	s := newTestServer(t)
+	admin, adminID := s.signup("boss")
+	_, userID := s.signup("alice")
+	s.do("POST", "/api/admin/users/"+userID+"/coins", admin, map[string]interface{}{"delta": 10, "reason": "test"})
+
+	for _, path := range []string{"/api/admin/audit", "/api/user/transactions/" + adminID} {
+		rec := s.do("GET", path+"?page=9223372036854775807&limit=100", admin, nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s : %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+}
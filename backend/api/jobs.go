@@ -0,0 +1,15 @@
+package api
+
+import (
+	"context"
+	"quizmaster/api/handlers"
+)
+
+// StartJobs lance les tâches de fond du serveur, arrêtées à l'annulation de ctx
+func StartJobs(ctx context.Context) {
+	handlers.StartQuestionPrefetcher(ctx)
+	handlers.StartExplanationWorker(ctx)
+	handlers.StartQuizExpiry(ctx)
+	handlers.StartRewardReconciler(ctx)
+	handlers.StartLedgerChecker(ctx)
+}
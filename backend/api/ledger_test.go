@@ -0,0 +1,37 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestLedgerCheck(t *testing.T) {
+	s := newTestServer(t)
+	admin, _ := s.signup("boss")
+	_, userID := s.signup("alice")
+
+	rec := s.do("POST", "/api/admin/users/"+userID+"/coins", admin, map[string]interface{}{"delta": 250, "reason": "test"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ajustement des pièces : %d %s", rec.Code, rec.Body.String())
+	}
+	var reports []struct {
+		Username string   `json:"username"`
+		Issues   []string `json:"issues"`
+	}
+	decode(t, s.do("GET", "/api/admin/ledger/check", admin, nil), &reports)
+	if len(reports) != 0 {
+		t.Fatalf("incohérences sur un journal à jour : %+v", reports)
+	}
+
+	// un compte dont les mouvements ne sont pas au journal est signalé
+	_, err := s.store.InsertUser(context.Background(), model.User{Username: "mallory", Coins: 5000, LedgerSeq: 2})
+	if err != nil {
+		t.Fatalf("InsertUser : %v", err)
+	}
+	decode(t, s.do("GET", "/api/admin/ledger/check", admin, nil), &reports)
+	if len(reports) != 1 || reports[0].Username != "mallory" || len(reports[0].Issues) == 0 {
+		t.Errorf("rapport de vérification : %+v", reports)
+	}
+}
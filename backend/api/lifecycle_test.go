@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAbandonAndResume(t *testing.T) {
+	s := newTestServer(t)
+	token, userID := s.signup("alice")
+	quizID := s.startQuiz(token, "alice")
+	s.do("POST", "/api/quiz/verifyAnswer", token, map[string]interface{}{"quizID": quizID, "answer": "A"})
+
+	// le quiz en cours est listé et repris à la question où il s'était arrêté
+	var ongoing []struct {
+		ID              string `json:"ID"`
+		Number_question int
+	}
+	rec := s.do("GET", "/api/quiz/ongoing", token, nil)
+	if decode(t, rec, &ongoing); len(ongoing) != 1 || ongoing[0].ID != quizID || ongoing[0].Number_question != 1 {
+		t.Fatalf("quiz en cours : %d %s", rec.Code, rec.Body.String())
+	}
+	var resumed struct {
+		Number_question int
+		Mark            int
+	}
+	rec = s.do("GET", "/api/quiz/resume/"+quizID, token, nil)
+	if decode(t, rec, &resumed); rec.Code != http.StatusOK || resumed.Number_question != 1 || resumed.Mark != 1 {
+		t.Fatalf("reprise : %d %s", rec.Code, rec.Body.String())
+	}
+	before := s.user(userID)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		want   int
+	}{
+		{"abandon", "POST", "/api/quiz/abandon/" + quizID, nil, http.StatusOK},
+		{"second abandon", "POST", "/api/quiz/abandon/" + quizID, nil, http.StatusConflict},
+		{"reprise d'un quiz abandonné", "GET", "/api/quiz/resume/" + quizID, nil, http.StatusConflict},
+		{"réponse à un quiz abandonné", "POST", "/api/quiz/verifyAnswer", map[string]interface{}{"quizID": quizID, "answer": "A"}, http.StatusConflict},
+		{"quiz inconnu", "POST", "/api/quiz/abandon/inconnu", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.do(tt.method, tt.path, token, tt.body); rec.Code != tt.want {
+				t.Errorf("%s %s : %d, attendu %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	// un quiz abandonné ne rapporte rien et est compté dans les statistiques
+	after := s.user(userID)
+	if after.Coins != before.Coins || after.Stats.AbandonedQuizzes != 1 {
+		t.Errorf("après l'abandon : %d pièces (avant %d), statistiques %+v", after.Coins, before.Coins, after.Stats)
+	}
+	decode(t, s.do("GET", "/api/quiz/ongoing", token, nil), &ongoing)
+	if len(ongoing) != 0 {
+		t.Errorf("quiz abandonné encore en cours : %+v", ongoing)
+	}
+
+	// un nouveau quiz peut être commencé dans la même catégorie
+	var quiz struct {
+		ID string `json:"ID"`
+	}
+	rec = s.do("POST", "/api/quiz/createQuiz/Tests", token, map[string]string{"username": "alice", "categoryname": "Tests"})
+	if decode(t, rec, &quiz); rec.Code != http.StatusOK || quiz.ID == quizID {
+		t.Errorf("nouveau quiz : %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// envoie une connexion depuis l'adresse donnée, les compteurs par IP sont partagés entre les tests
+func (s *testServer) login(username, password, remoteAddr string) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(map[string]string{"Username": username, "Password": password})
+	req := httptest.NewRequest("POST", "/api/user/login", bytes.NewReader(data))
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLoginThrottling(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("throttled")
+	login := func(password string) *httptest.ResponseRecorder {
+		return s.login("throttled", password, "198.51.100.7:4321")
+	}
+
+	// les premiers échecs sont autorisés sans attente
+	for i := 0; i < 3; i++ {
+		if rec := login("mauvais"); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("échec %d : %d, attendu %d", i+1, rec.Code, http.StatusUnauthorized)
+		}
+	}
+	// au-delà, les tentatives sont ralenties, même avec le bon mot de passe
+	login("mauvais")
+	rec := login("Passw0rd!x")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("tentative ralentie : %d, attendu %d (%s)", rec.Code, http.StatusTooManyRequests, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("en-tête Retry-After absent")
+	}
+}
+
+// des connexions lancées en parallèle ne contournent pas le ralentissement
+func TestLoginThrottlingParallel(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("parallel")
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		codes = map[int]int{}
+	)
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			rec := s.login("parallel", "mauvais", "198.51.100.8:4321")
+			mu.Lock()
+			codes[rec.Code]++
+			mu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if codes[http.StatusUnauthorized] != 3 || codes[http.StatusTooManyRequests] != 17 {
+		t.Errorf("réponses : %v, attendu 3 refus du mot de passe et 17 ralentissements", codes)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOwnershipEnforced(t *testing.T) {
+	s := newTestServer(t)
+	alice, aliceID := s.signup("alice")
+	bob, bobID := s.signup("bob")
+	quizID := s.startQuiz(alice, "alice")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		want   int
+	}{
+		{"renommage sans token", "PUT", "/api/user/changeUsername/" + aliceID, "", map[string]string{"newUsername": "x"}, http.StatusUnauthorized},
+		{"renommage d'un autre joueur", "PUT", "/api/user/changeUsername/" + aliceID, bob, map[string]string{"newUsername": "x"}, http.StatusForbidden},
+		{"suppression d'un autre joueur", "DELETE", "/api/user/deleteUser/" + aliceID, bob, nil, http.StatusForbidden},
+		{"déconnexion d'un autre joueur", "POST", "/api/user/logout/" + aliceID, bob, nil, http.StatusForbidden},
+		{"transactions d'un autre joueur", "GET", "/api/user/transactions/" + aliceID, bob, nil, http.StatusForbidden},
+		{"quiz créé au nom d'un autre joueur", "POST", "/api/quiz/createQuiz/Tests", bob, map[string]string{"username": "alice", "categoryname": "Tests"}, http.StatusForbidden},
+		{"tirage au nom d'un autre joueur", "POST", "/api/gacha/pull", bob, map[string]interface{}{"username": "alice", "quantity": 1}, http.StatusForbidden},
+		{"réponse au quiz d'un autre joueur", "POST", "/api/quiz/verifyAnswer", bob, map[string]interface{}{"quizID": quizID, "answer": "A"}, http.StatusForbidden},
+		{"abandon du quiz d'un autre joueur", "POST", "/api/quiz/abandon/" + quizID, bob, nil, http.StatusForbidden},
+		{"ses propres transactions", "GET", "/api/user/transactions/" + bobID, bob, nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.do(tt.method, tt.path, tt.token, tt.body); rec.Code != tt.want {
+				t.Errorf("%s %s : %d, attendu %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	// aucune des requêtes refusées n'a modifié le compte ni le quiz
+	if user := s.user(aliceID); user.Username != "alice" {
+		t.Errorf("compte modifié : %+v", user)
+	}
+	if rec := s.do("GET", "/api/quiz/resume/"+quizID, alice, nil); rec.Code != http.StatusOK {
+		t.Errorf("reprise du quiz : %d %s", rec.Code, rec.Body.String())
+	}
+}
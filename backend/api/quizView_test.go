@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"quizmaster/model"
+	"testing"
+)
+
+// champs des bonnes réponses d'une question, qui ne doivent pas apparaître avant la réponse
+var answerFields = [][]byte{[]byte("response_correct"), []byte("responses_correct"), []byte("aliases"), []byte("tolerance")}
+
+func assertNoAnswers(t *testing.T, name string, body []byte) {
+	t.Helper()
+	for _, field := range answerFields {
+		if bytes.Contains(body, field) {
+			t.Errorf("%s : champ %s présent dans %s", name, field, body)
+		}
+	}
+}
+
+func TestCategoriesHideAnswers(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.signup("alice")
+	bob, _ := s.signup("bob")
+	s.startQuiz(alice, "alice")
+
+	// la liste publique ne contient que le résumé des catégories
+	rec := s.do("GET", "/api/user/getUserCategories", "", nil)
+	var summaries []struct {
+		Username      string
+		CategoryName  string
+		QuestionCount int
+	}
+	if decode(t, rec, &summaries); rec.Code != http.StatusOK || len(summaries) != 1 || summaries[0].QuestionCount != 10 || summaries[0].Username != "alice" {
+		t.Fatalf("liste des catégories : %d %s", rec.Code, rec.Body.String())
+	}
+	assertNoAnswers(t, "liste des catégories", rec.Body.Bytes())
+	if bytes.Contains(rec.Body.Bytes(), []byte("Question a")) {
+		t.Errorf("questions présentes dans la liste des catégories : %s", rec.Body.String())
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"sans token", "/api/user/category/Tests", "", http.StatusUnauthorized},
+		{"autre joueur", "/api/user/category/Tests", bob, http.StatusForbidden},
+		{"catégorie inconnue", "/api/user/category/Inconnue", alice, http.StatusNotFound},
+		{"créateur", "/api/user/category/Tests", alice, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.do("GET", tt.path, tt.token, nil); rec.Code != tt.want {
+				t.Errorf("GET %s : %d, attendu %d (%s)", tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	// le créateur reçoit les questions complètes pour modifier sa catégorie
+	var category model.Category
+	decode(t, s.do("GET", "/api/user/category/Tests", alice, nil), &category)
+	if len(category.Questions) != 10 || category.Questions[0].ResponseCorrect != "A" {
+		t.Errorf("catégorie du créateur : %+v", category)
+	}
+}
+
+func TestQuizViewHidesAnswers(t *testing.T) {
+	s := newTestServer(t)
+	token, _ := s.signup("alice")
+	quizID := s.startQuiz(token, "alice")
+
+	// la vue du quiz ne montre que la question en cours, sans sa réponse
+	rec := s.do("GET", "/api/quiz/resume/"+quizID, token, nil)
+	var view struct {
+		Number_question int
+		Amount          int
+		Question        *struct {
+			QuestionText string   `json:"question_text"`
+			Responses    []string `json:"responses"`
+		}
+	}
+	if decode(t, rec, &view); rec.Code != http.StatusOK || view.Question == nil || len(view.Question.Responses) != 4 || view.Amount != 10 {
+		t.Fatalf("reprise du quiz : %d %s", rec.Code, rec.Body.String())
+	}
+	assertNoAnswers(t, "vue du quiz", rec.Body.Bytes())
+	if bytes.Count(rec.Body.Bytes(), []byte("question_text")) != 1 {
+		t.Errorf("plusieurs questions dans la vue du quiz : %s", rec.Body.String())
+	}
+
+	// la bonne réponse est révélée après la réponse, la question suivante reste cachée
+	rec = s.do("POST", "/api/quiz/verifyAnswer", token, map[string]interface{}{"quizID": quizID, "answer": "B"})
+	var result struct {
+		Correct        bool     `json:"correct"`
+		CorrectAnswers []string `json:"correctAnswers"`
+	}
+	if decode(t, rec, &result); result.Correct || len(result.CorrectAnswers) != 1 || result.CorrectAnswers[0] != "A" {
+		t.Errorf("résultat de la réponse : %s", rec.Body.String())
+	}
+	assertNoAnswers(t, "question suivante", rec.Body.Bytes())
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"quizmaster/db"
+	"quizmaster/mail"
+	"testing"
+	"time"
+)
+
+// testMailer transmet les e-mails envoyés au test
+type testMailer chan mail.Message
+
+func (m testMailer) Send(ctx context.Context, msg mail.Message) error {
+	m <- msg
+	return nil
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	mailer := make(testMailer, 1)
+	s.router = ConfigureRoutes(s.store, mailer)
+	credentials := map[string]string{"Username": "alice", "Password": "Passw0rd!x", "Email": "alice@example.com"}
+	if rec := s.do("POST", "/api/user/createUser", "", credentials); rec.Code != http.StatusCreated {
+		t.Fatalf("création du compte : %d %s", rec.Code, rec.Body.String())
+	}
+	var login db.LoginResponse
+	json.Unmarshal(s.do("POST", "/api/user/login", "", credentials).Body.Bytes(), &login)
+
+	// la réponse ne dépend pas de l'existence du compte
+	unknown := s.do("POST", "/api/user/requestPasswordReset", "", map[string]string{"username": "inconnu"})
+	rec := s.do("POST", "/api/user/requestPasswordReset", "", map[string]string{"username": "alice"})
+	if rec.Code != http.StatusOK || rec.Body.String() != unknown.Body.String() {
+		t.Fatalf("demande de réinitialisation : %d %s, compte inconnu : %s", rec.Code, rec.Body.String(), unknown.Body.String())
+	}
+
+	var msg mail.Message
+	select {
+	case msg = <-mailer:
+	case <-time.After(time.Second):
+		t.Fatal("aucun e-mail envoyé")
+	}
+	if msg.To != "alice@example.com" || len(msg.Secrets) != 1 {
+		t.Fatalf("e-mail envoyé : %+v", msg)
+	}
+	token := msg.Secrets[0]
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		want     int
+	}{
+		{"token inconnu", "inconnu", "N0uveau!pass", http.StatusBadRequest},
+		{"mot de passe refusé", token, "court", http.StatusBadRequest},
+		{"réinitialisation", token, "N0uveau!pass", http.StatusOK},
+		{"token déjà utilisé", token, "Autre!pass9", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]string{"token": tt.token, "newPassword": tt.password}
+			if rec := s.do("POST", "/api/user/resetPassword", "", body); rec.Code != tt.want {
+				t.Errorf("réinitialisation : %d, attendu %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	if response, _ := db.Login(context.Background(), s.store, "alice", "N0uveau!pass"); response.Status != http.StatusOK {
+		t.Errorf("connexion avec le nouveau mot de passe : %+v", response)
+	}
+	if response, _ := db.Login(context.Background(), s.store, "alice", "Passw0rd!x"); response.Status != http.StatusUnauthorized {
+		t.Errorf("connexion avec l'ancien mot de passe : %+v", response)
+	}
+	// les sessions ouvertes avant la réinitialisation sont fermées
+	if rec := s.do("GET", "/api/user/getUserToken", login.Token, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("session ouverte avant la réinitialisation : %d, attendu %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPasswordResetDisabled(t *testing.T) {
+	s := newTestServer(t)
+	rec := s.do("POST", "/api/user/requestPasswordReset", "", map[string]string{"username": "alice"})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("réinitialisation sans transport : %d, attendu %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
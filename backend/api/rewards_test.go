@@ -0,0 +1,50 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"quizmaster/db"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestQuizRewardsCredited(t *testing.T) {
+	s := newTestServer(t)
+	token, userID := s.signup("alice")
+	quizID := s.startQuiz(token, "alice")
+	before := s.user(userID)
+
+	for i := 0; i < 10; i++ {
+		rec := s.do("POST", "/api/quiz/verifyAnswer", token, map[string]interface{}{"quizID": quizID, "answer": "A"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("réponse %d : %d %s", i, rec.Code, rec.Body.String())
+		}
+	}
+
+	after := s.user(userID)
+	if after.Coins <= before.Coins || after.Experience <= before.Experience {
+		t.Errorf("récompenses non créditées : %d pièces et %d XP, avant %d et %d", after.Coins, after.Experience, before.Coins, before.Experience)
+	}
+	if after.Stats.PlayedQuizzes != 1 || after.Stats.FullMarks != 1 || after.Stats.CorrectResponses != 10 {
+		t.Errorf("statistiques après le quiz : %+v", after.Stats)
+	}
+	quiz, _ := s.store.GetQuizByID(context.Background(), quizID)
+	if !quiz.RewardsApplied {
+		t.Error("le quiz n'est pas marqué comme récompensé")
+	}
+
+	// un second crédit, par exemple par la réconciliation, est sans effet
+	err := s.store.CreditQuizRewards(context.Background(), "alice", quizID, model.QuizReward{Coins: 100})
+	if err != db.ErrNoChange {
+		t.Errorf("second crédit : %v, attendu %v", err, db.ErrNoChange)
+	}
+	if again := s.user(userID); again.Coins != after.Coins {
+		t.Errorf("%d pièces après un second crédit, attendu %d", again.Coins, after.Coins)
+	}
+
+	var entries []model.LedgerEntry
+	decode(t, s.do("GET", "/api/user/transactions/"+userID, token, nil), &entries)
+	if len(entries) != 1 || entries[0].Kind != model.LedgerQuizReward || entries[0].CoinsAfter != after.Coins {
+		t.Errorf("journal après le quiz : %+v", entries)
+	}
+}
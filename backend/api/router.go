@@ -1,10 +1,16 @@
 package api
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"quizmaster/api/handlers"
+	"quizmaster/config"
+	"quizmaster/db"
+	"quizmaster/mail"
+	"quizmaster/model"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -16,41 +22,162 @@ func request(next http.Handler) http.Handler {
 	})
 }
 
-// gestion des routes
-func ConfigureRoutes() *mux.Router {
+// extrait le token de l'en-tête Authorization, avec ou sans le préfixe "Bearer "
+func bearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
+
+// authenticate résout le token de la requête en utilisateur et le place dans le contexte.
+// Les requêtes sans token valide reçoivent une réponse 401.
+func authenticate(store db.Store, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unauthorized := func(message string) {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusUnauthorized, Message: message})
+		}
+
+		token := bearerToken(r)
+		if token == "" {
+			unauthorized("Token manquant")
+			return
+		}
+
+		user, session, err := db.Authenticate(r.Context(), store, token)
+		if err == db.ErrInvalidToken || err == db.ErrExpiredToken {
+			unauthorized("Session invalide ou expirée")
+			return
+		}
+		if err != nil {
+			log.Printf("Erreur lors de l'authentification : %v", err)
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return
+		}
+
+		if user.Banned {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusForbidden, Message: "Compte banni"})
+			return
+		}
+
+		next(w, r.WithContext(handlers.WithUser(r.Context(), user, session)))
+	}
+}
+
+// requireRole n'autorise que les utilisateurs ayant au moins le rôle donné (403 sinon).
+// Doit être placé derrière authenticate.
+func requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := handlers.CurrentUser(r)
+		if !ok || !user.HasRole(role) {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(model.ApiResponse{Status: http.StatusForbidden, Message: "Privilèges insuffisants"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// gestion des routes, les handlers utilisent le stockage store et envoient les e-mails par mailer
+func ConfigureRoutes(store db.Store, mailer mail.Mailer) *mux.Router {
+	handlers.SetStore(store)
+	handlers.SetMailer(mailer)
+
+	// auth protège un handler par le middleware d'authentification
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return authenticate(store, next)
+	}
+	// moderator et admin exigent en plus le rôle correspondant
+	moderator := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth(requireRole(model.RoleModerator, next))
+	}
+	admin := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth(requireRole(model.RoleAdmin, next))
+	}
+	// creator protège la création de catégories et de questions : ouverte à tous les joueurs par
+	// défaut, réservée au rôle "creator" et au-dessus avec CATEGORY_CREATION_ROLE=creator
+	creationRole := config.String("CATEGORY_CREATION_ROLE", model.RolePlayer)
+	if !model.ValidRole(creationRole) {
+		log.Printf("CATEGORY_CREATION_ROLE inconnu : %q, la création reste ouverte aux joueurs", creationRole)
+		creationRole = model.RolePlayer
+	}
+	creator := func(next http.HandlerFunc) http.HandlerFunc {
+		return auth(requireRole(creationRole, next))
+	}
+
 	r := mux.NewRouter() // r est l'objet Router de mux qui gère le routage des requêtes HTTP
 	r.Use(request)       // appeler request pour chaque requête
 
 	// Handlers pour les endpoints de l'API utilisateur
 	r.HandleFunc("/api/user/createUser", handlers.CreateUserHandler).Methods("POST")
-	r.HandleFunc("/api/user/getall/", handlers.GetAllUsersHandler).Methods("GET")
-	r.HandleFunc("/api/user/getUserToken", handlers.GetUserTokenHandler).Methods("GET")
+	r.HandleFunc("/api/user/getall/", moderator(handlers.GetAllUsersHandler)).Methods("GET")
+	r.HandleFunc("/api/user/getUserToken", auth(handlers.GetUserTokenHandler)).Methods("GET")
 	r.HandleFunc("/api/user/login", handlers.LoginHandler).Methods("POST")
-	r.HandleFunc("/api/user/logout/{userid}", handlers.LogoutHandler).Methods("POST")
-	r.HandleFunc("/api/user/changeUsername/{userid}", handlers.UpdateUserUsernameHandler).Methods("PUT")
-	r.HandleFunc("/api/user/changePassword/{userid}", handlers.UpdateUserPasswordHandler).Methods("PUT")
-	r.HandleFunc("/api/user/changePicture/{userid}", handlers.UpdateUserPictureHandler).Methods("PUT")
-	r.HandleFunc("/api/user/deleteUser/{userid}", handlers.DeleteUserHandler).Methods("DELETE")
+	r.HandleFunc("/api/user/refresh", handlers.RefreshHandler).Methods("POST")
+	r.HandleFunc("/api/user/logout/{userid}", auth(handlers.LogoutHandler)).Methods("POST")
+	r.HandleFunc("/api/user/logoutAll", auth(handlers.LogoutAllHandler)).Methods("POST")
+	r.HandleFunc("/api/user/changeUsername/{userid}", auth(handlers.UpdateUserUsernameHandler)).Methods("PUT")
+	r.HandleFunc("/api/user/changePassword/{userid}", auth(handlers.UpdateUserPasswordHandler)).Methods("PUT")
+	r.HandleFunc("/api/user/changeEmail/{userid}", auth(handlers.UpdateUserEmailHandler)).Methods("PUT")
+	r.HandleFunc("/api/user/requestPasswordReset", handlers.RequestPasswordResetHandler).Methods("POST")
+	r.HandleFunc("/api/user/resetPassword", handlers.ResetPasswordHandler).Methods("POST")
+	r.HandleFunc("/api/user/changePicture/{userid}", auth(handlers.UpdateUserPictureHandler)).Methods("PUT")
+	r.HandleFunc("/api/user/deleteUser/{userid}", auth(handlers.DeleteUserHandler)).Methods("DELETE")
 	r.HandleFunc("/api/user/getUserCategories", handlers.GetUserCategoriesHandler).Methods("GET")
-	r.HandleFunc("/api/user/createCategory", handlers.CreateCategoryHandler).Methods("POST")
-	r.HandleFunc("/api/user/updateCategory", handlers.UpdateCategoryHandler).Methods("PUT")
+	r.HandleFunc("/api/user/category/{category}", creator(handlers.GetOwnCategoryHandler)).Methods("GET")
+	r.HandleFunc("/api/user/createCategory", creator(handlers.CreateCategoryHandler)).Methods("POST")
+	r.HandleFunc("/api/user/updateCategory", creator(handlers.UpdateCategoryHandler)).Methods("PUT")
 	r.HandleFunc("/api/user/getUser/{username}", handlers.GetUserByNameHandler).Methods("GET")
 	r.HandleFunc("/api/user/getTopPlayers", handlers.GetTopPlayers).Methods("GET")
+	r.HandleFunc("/api/user/quizHistory/{userid}", auth(handlers.QuizHistoryHandler)).Methods("GET")
+	r.HandleFunc("/api/user/transactions/{userid}", auth(handlers.TransactionsHandler)).Methods("GET")
 
 	// Handlers pour les endpoints de l'API quiz
-	r.HandleFunc("/api/quiz/getQuizByExternalAPI/{category}", handlers.GenerateQuizHandler).Methods("GET")
-	r.HandleFunc("/api/quiz/verifyAnswer", handlers.VerifyAnswer).Methods("POST")
-	r.HandleFunc("/api/quiz/createQuestion", handlers.CreateQuestionHandler).Methods("POST")
-	r.HandleFunc("/api/quiz/createQuiz/{category}", handlers.CreateQuizHandler).Methods("POST")
+	r.HandleFunc("/api/quiz/externalCategories", handlers.ExternalCategoriesHandler).Methods("GET")
+	r.HandleFunc("/api/quiz/getQuizByExternalAPI/{category}", auth(handlers.GenerateQuizHandler)).Methods("GET")
+	r.HandleFunc("/api/quiz/verifyAnswer", auth(handlers.VerifyAnswer)).Methods("POST")
+	r.HandleFunc("/api/quiz/createQuestion", creator(handlers.CreateQuestionHandler)).Methods("POST")
+	r.HandleFunc("/api/quiz/createQuiz/{category}", auth(handlers.CreateQuizHandler)).Methods("POST")
+	r.HandleFunc("/api/quiz/review/{quizid}", auth(handlers.ReviewQuizHandler)).Methods("GET")
+	r.HandleFunc("/api/quiz/ongoing", auth(handlers.OnGoingQuizzesHandler)).Methods("GET")
+	r.HandleFunc("/api/quiz/resume/{quizid}", auth(handlers.ResumeQuizHandler)).Methods("GET")
+	r.HandleFunc("/api/quiz/abandon/{quizid}", auth(handlers.AbandonQuizHandler)).Methods("POST")
 
 	// Handlers pour les endpoints de l'API AIMLAPI
-	r.HandleFunc("/api/chat", handlers.ChatHandler).Methods("POST")
+	r.HandleFunc("/api/chat", auth(handlers.ChatHandler)).Methods("POST")
 
 	// Handlers pour les endpoints de l'API gacha
-	r.HandleFunc("/api/gacha/pull", handlers.PullHandler).Methods("POST")
+	r.HandleFunc("/api/gacha/banners", auth(handlers.BannersHandler)).Methods("GET")
+	r.HandleFunc("/api/gacha/pull", auth(handlers.PullHandler)).Methods("POST")
 
 	// Handlers pour cheatSheet
-	r.HandleFunc("/api/cheatsheet", handlers.UseCheatSheetHandler).Methods("POST")
+	r.HandleFunc("/api/cheatsheet", auth(handlers.UseCheatSheetHandler)).Methods("POST")
+
+	// Handlers pour l'administration
+	r.HandleFunc("/api/admin/users", moderator(handlers.AdminListUsersHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/users/{userid}/ban", moderator(handlers.AdminBanUserHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/users/{userid}/unban", moderator(handlers.AdminUnbanUserHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/users/{userid}/unlock", moderator(handlers.AdminUnlockUserHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/lockouts", moderator(handlers.AdminLockoutsHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/lockouts/ips/{ip}", moderator(handlers.AdminUnlockIPHandler)).Methods("DELETE")
+	r.HandleFunc("/api/admin/users/{userid}/role", admin(handlers.AdminSetRoleHandler)).Methods("PUT")
+	r.HandleFunc("/api/admin/users/{userid}/coins", admin(handlers.AdminAdjustCoinsHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/users/{userid}/inventory", admin(handlers.AdminAdjustInventoryHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/categories", moderator(handlers.AdminListCategoriesHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/categories/{category}", moderator(handlers.AdminDeleteCategoryHandler)).Methods("DELETE")
+	r.HandleFunc("/api/admin/categories/{category}/hide", moderator(handlers.AdminHideCategoryHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/categories/{category}/unhide", moderator(handlers.AdminUnhideCategoryHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/quizzes/{quizid}/finish", moderator(handlers.AdminFinishQuizHandler)).Methods("POST")
+	r.HandleFunc("/api/admin/audit", moderator(handlers.AdminAuditHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/ledger/check", admin(handlers.AdminCheckLedgerHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/banners", admin(handlers.AdminListBannersHandler)).Methods("GET")
+	r.HandleFunc("/api/admin/banners/{bannerid}", admin(handlers.AdminSaveBannerHandler)).Methods("PUT")
 
 	buildDir := "../client/build"
 	fileServer := http.FileServer(http.Dir(buildDir))
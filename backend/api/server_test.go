@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"quizmaster/db"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// les handlers journalisent chaque requête
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testServer envoie des requêtes aux routes de l'API, sur un stockage en mémoire
+type testServer struct {
+	t      *testing.T
+	store  *db.MemoryStore
+	router http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Setenv("ADMIN_USERNAMES", "boss")
+	store := db.NewMemoryStore()
+	return &testServer{t: t, store: store, router: ConfigureRoutes(store, nil)}
+}
+
+// envoie une requête avec le token donné et un corps JSON facultatif
+func (s *testServer) do(method, path, token string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("encodage du corps : %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// décode une réponse model.ApiResponse, Data dans data si non nil
+func decode(t *testing.T, rec *httptest.ResponseRecorder, data interface{}) model.ApiResponse {
+	t.Helper()
+	var response struct {
+		model.ApiResponse
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("réponse illisible (%d) : %s", rec.Code, rec.Body.String())
+	}
+	if data != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			t.Fatalf("données illisibles : %s", response.Data)
+		}
+	}
+	return response.ApiResponse
+}
+
+// crée un compte, s'y connecte et retourne son token et son ID
+func (s *testServer) signup(username string) (string, string) {
+	s.t.Helper()
+	credentials := map[string]string{"Username": username, "Password": "Passw0rd!x"}
+	if rec := s.do("POST", "/api/user/createUser", "", credentials); rec.Code != http.StatusCreated {
+		s.t.Fatalf("création de %s : %d %s", username, rec.Code, rec.Body.String())
+	}
+	rec := s.do("POST", "/api/user/login", "", credentials)
+	var login db.LoginResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &login); err != nil || login.Token == "" {
+		s.t.Fatalf("connexion de %s : %d %s", username, rec.Code, rec.Body.String())
+	}
+	return login.Token, login.UserID
+}
+
+// crée une catégorie de dix questions dont la bonne réponse est "A" et démarre un quiz
+func (s *testServer) startQuiz(token, username string) string {
+	s.t.Helper()
+	questions := []model.Question{}
+	for i := 0; i < 10; i++ {
+		questions = append(questions, model.Question{
+			QuestionText:    "Question " + string(rune('a'+i)),
+			Responses:       []string{"A", "B", "C", "D"},
+			ResponseCorrect: "A",
+		})
+	}
+	rec := s.do("POST", "/api/user/createCategory", token, map[string]interface{}{
+		"username": username, "categoryName": "Tests", "questions": questions,
+	})
+	if rec.Code >= 300 {
+		s.t.Fatalf("création de la catégorie : %d %s", rec.Code, rec.Body.String())
+	}
+
+	var quiz model.Quiz
+	rec = s.do("POST", "/api/quiz/createQuiz/Tests", token, map[string]string{"username": username, "categoryname": "Tests"})
+	if decode(s.t, rec, &quiz); rec.Code != http.StatusOK || quiz.ID == "" {
+		s.t.Fatalf("création du quiz : %d %s", rec.Code, rec.Body.String())
+	}
+	return quiz.ID
+}
+
+func (s *testServer) user(userID string) model.User {
+	s.t.Helper()
+	user, err := s.store.GetUserByID(context.Background(), userID)
+	if err != nil {
+		s.t.Fatalf("GetUserByID : %v", err)
+	}
+	return user
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"quizmaster/db"
+	"testing"
+)
+
+func TestSessionLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	s.signup("alice")
+
+	var login db.LoginResponse
+	rec := s.do("POST", "/api/user/login", "", map[string]string{"Username": "alice", "Password": "Passw0rd!x"})
+	if err := json.Unmarshal(rec.Body.Bytes(), &login); err != nil || login.RefreshToken == "" || login.ExpiresAt == nil {
+		t.Fatalf("connexion : %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := s.do("GET", "/api/user/getUserToken", login.Token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("token après connexion : %d %s", rec.Code, rec.Body.String())
+	}
+
+	// le rafraîchissement remplace les deux tokens
+	var refreshed db.LoginResponse
+	rec = s.do("POST", "/api/user/refresh", "", map[string]string{"refreshToken": login.RefreshToken})
+	if err := json.Unmarshal(rec.Body.Bytes(), &refreshed); err != nil || rec.Code != http.StatusOK || refreshed.Token == login.Token {
+		t.Fatalf("rafraîchissement : %d %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		want   int
+	}{
+		{"ancien token après rotation", "GET", "/api/user/getUserToken", login.Token, nil, http.StatusUnauthorized},
+		{"ancien token de rafraîchissement", "POST", "/api/user/refresh", "", map[string]string{"refreshToken": login.RefreshToken}, http.StatusUnauthorized},
+		{"nouveau token", "GET", "/api/user/getUserToken", refreshed.Token, nil, http.StatusOK},
+		{"déconnexion", "POST", "/api/user/logout/" + refreshed.UserID, refreshed.Token, nil, http.StatusOK},
+		{"token révoqué", "GET", "/api/user/getUserToken", refreshed.Token, nil, http.StatusUnauthorized},
+		{"rafraîchissement après déconnexion", "POST", "/api/user/refresh", "", map[string]string{"refreshToken": refreshed.RefreshToken}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := s.do(tt.method, tt.path, tt.token, tt.body); rec.Code != tt.want {
+				t.Errorf("%s %s : %d, attendu %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	s := newTestServer(t)
+	first, _ := s.signup("alice")
+	second, _ := s.signup("alice2")
+	var other db.LoginResponse
+	rec := s.do("POST", "/api/user/login", "", map[string]string{"Username": "alice", "Password": "Passw0rd!x"})
+	json.Unmarshal(rec.Body.Bytes(), &other)
+
+	if rec := s.do("POST", "/api/user/logoutAll", first, nil); rec.Code != http.StatusOK {
+		t.Fatalf("déconnexion de toutes les sessions : %d %s", rec.Code, rec.Body.String())
+	}
+	for name, token := range map[string]string{"première session": first, "seconde session": other.Token} {
+		if rec := s.do("GET", "/api/user/getUserToken", token, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s : %d, attendu %d", name, rec.Code, http.StatusUnauthorized)
+		}
+	}
+	// les sessions des autres joueurs restent ouvertes
+	if rec := s.do("GET", "/api/user/getUserToken", second, nil); rec.Code != http.StatusOK {
+		t.Errorf("session d'un autre joueur : %d, attendu %d", rec.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// reçue après la limite de temps, une bonne réponse n'est pas comptée
+func TestLateAnswer(t *testing.T) {
+	for _, mode := range []string{"wrong", "skip"} {
+		t.Run(mode, func(t *testing.T) {
+			t.Setenv("QUIZ_LATE_ANSWERS", mode)
+			s := newTestServer(t)
+			token, _ := s.signup("alice")
+			quizID := s.startQuiz(token, "alice")
+
+			// la première question a été servie il y a une minute, avec 10 secondes pour répondre
+			ctx := context.Background()
+			quiz, _ := s.store.GetQuizByID(ctx, quizID)
+			quiz.TimeLimit = 10
+			quiz.Answers[0].ServedAt = time.Now().Add(-time.Minute)
+			if err := s.store.UpdateQuiz(ctx, quiz); err != nil {
+				t.Fatalf("UpdateQuiz : %v", err)
+			}
+
+			var result struct {
+				Correct       bool `json:"correct"`
+				Late          bool `json:"late"`
+				Skipped       bool `json:"skipped"`
+				Mark          int  `json:"mark"`
+				NextQuestion  int  `json:"nextQuestion"`
+				RemainingTime *int `json:"remainingTime"`
+			}
+			rec := s.do("POST", "/api/quiz/verifyAnswer", token, map[string]interface{}{"quizID": quizID, "answer": "A"})
+			if decode(t, rec, &result); rec.Code != http.StatusOK {
+				t.Fatalf("réponse : %d %s", rec.Code, rec.Body.String())
+			}
+			if result.Correct || !result.Late || result.Mark != 0 || result.Skipped != (mode == "skip") {
+				t.Errorf("réponse en retard : %+v", result)
+			}
+			// la question suivante a son propre chronomètre
+			if result.NextQuestion != 1 || result.RemainingTime == nil || *result.RemainingTime != 10 {
+				t.Errorf("question suivante : %d, temps restant %v", result.NextQuestion, result.RemainingTime)
+			}
+		})
+	}
+}
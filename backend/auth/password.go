@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"quizmaster/config"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt ignore tout ce qui dépasse 72 octets
+const maxPasswordBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// liste des mots de passe courants, chargée une seule fois au démarrage
+var commonPasswords = loadCommonPasswords(commonPasswordsFile)
+
+func loadCommonPasswords(content string) map[string]bool {
+	passwords := make(map[string]bool)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = true
+	}
+	return passwords
+}
+
+// PasswordPolicyError décrit pourquoi un mot de passe est refusé
+type PasswordPolicyError struct {
+	Reason string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Reason
+}
+
+// ValidatePassword vérifie que le mot de passe respecte la politique configurée :
+// longueur minimale (PASSWORD_MIN_LENGTH, 8 par défaut), 72 octets au plus,
+// différent du nom d'utilisateur et absent de la liste des mots de passe courants.
+func ValidatePassword(password, username string) error {
+	minLength := config.Int("PASSWORD_MIN_LENGTH", 8)
+	if utf8.RuneCountInString(password) < minLength {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("Le mot de passe doit contenir au moins %d caractères", minLength)}
+	}
+	if len(password) > maxPasswordBytes {
+		return &PasswordPolicyError{Reason: fmt.Sprintf("Le mot de passe ne doit pas dépasser %d octets", maxPasswordBytes)}
+	}
+	lower := strings.ToLower(password)
+	if username != "" && lower == strings.ToLower(username) {
+		return &PasswordPolicyError{Reason: "Le mot de passe doit être différent du nom d'utilisateur"}
+	}
+	if commonPasswords[lower] {
+		return &PasswordPolicyError{Reason: "Ce mot de passe est trop courant, choisissez-en un autre"}
+	}
+	return nil
+}
+
+// bcryptCost retourne le coût bcrypt configuré (BCRYPT_COST, bcrypt.DefaultCost par défaut)
+func bcryptCost() int {
+	cost := config.Int("BCRYPT_COST", bcrypt.DefaultCost)
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return bcrypt.DefaultCost
+	}
+	return cost
+}
+
+// HashPassword hache le mot de passe avec bcrypt au coût configuré
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost())
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword indique si password correspond au hash bcrypt
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// IsPolicyError indique si err provient de la politique de mots de passe
+func IsPolicyError(err error) bool {
+	var policyErr *PasswordPolicyError
+	return errors.As(err, &policyErr)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// au-delà de ce nombre de clés suivies, les clés expirées sont purgées
+const maxTrackedKeys = 10000
+
+// RateLimiter limite le nombre d'actions par clé sur une fenêtre glissante.
+// Les compteurs sont gardés en mémoire, ils sont propres à chaque instance du serveur.
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+}
+
+// NewRateLimiter autorise au plus limit actions par clé pendant window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, hits: make(map[string][]time.Time)}
+}
+
+// Allow enregistre une action pour key et indique si elle est autorisée
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	// nettoyage des clés inactives pour que la table ne grossisse pas indéfiniment
+	if len(l.hits) > maxTrackedKeys {
+		for k := range l.hits {
+			l.recent(k, now)
+		}
+	}
+
+	hits := l.recent(key, now)
+	if len(hits) >= l.limit {
+		l.hits[key] = hits
+		return false
+	}
+	l.hits[key] = append(hits, now)
+	return true
+}
+
+// retourne les actions de key encore dans la fenêtre, à appeler avec le verrou
+func (l *RateLimiter) recent(key string, now time.Time) []time.Time {
+	hits := l.hits[key]
+	i := 0
+	for i < len(hits) && now.Sub(hits[i]) >= l.window {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) == 0 {
+		delete(l.hits, key)
+		return nil
+	}
+	return hits
+}
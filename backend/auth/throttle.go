@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ThrottleConfig règle le ralentissement des tentatives de connexion
+type ThrottleConfig struct {
+	FreeAttempts    int           // échecs tolérés avant d'imposer un délai
+	BaseDelay       time.Duration // délai après le premier échec au-delà de FreeAttempts, doublé à chaque échec suivant
+	MaxDelay        time.Duration // délai maximal entre deux tentatives
+	MaxFailures     int           // nombre d'échecs provoquant un verrouillage
+	LockoutDuration time.Duration // durée du verrouillage
+	ResetAfter      time.Duration // oubli des échecs après cette durée sans nouvelle tentative ratée
+}
+
+// ThrottleStatus est l'état d'une clé (adresse IP ou nom d'utilisateur)
+type ThrottleStatus struct {
+	Key          string    `json:"key"`
+	Failures     int       `json:"failures"`
+	LastFailure  time.Time `json:"lastFailure"`
+	BlockedUntil time.Time `json:"blockedUntil"`
+	Locked       bool      `json:"locked"`
+
+	// tentatives autorisées par Check dont le résultat n'est pas encore connu
+	pending    int
+	reservedAt time.Time
+}
+
+// durée après laquelle une tentative réservée sans résultat est oubliée
+const reservationTimeout = time.Minute
+
+// Throttle compte les échecs de connexion par clé et impose un délai croissant
+// (backoff exponentiel) puis un verrouillage temporaire.
+// Chaque tentative autorisée par Check est réservée jusqu'à l'appel de Failure,
+// Success ou Release, pour que des tentatives parallèles ne contournent pas le délai.
+// Les compteurs sont gardés en mémoire, ils sont propres à chaque instance du serveur.
+type Throttle struct {
+	mu     sync.Mutex
+	config ThrottleConfig
+	states map[string]*ThrottleStatus
+}
+
+// NewThrottle crée un Throttle avec la configuration donnée
+func NewThrottle(config ThrottleConfig) *Throttle {
+	return &Throttle{config: config, states: make(map[string]*ThrottleStatus)}
+}
+
+// retourne l'état de key s'il est encore pertinent, à appeler avec le verrou
+func (t *Throttle) state(key string, now time.Time) *ThrottleStatus {
+	st, ok := t.states[key]
+	if !ok {
+		return nil
+	}
+	if st.pending > 0 && now.Sub(st.reservedAt) > reservationTimeout {
+		st.pending = 0
+	}
+	if st.pending == 0 && now.After(st.BlockedUntil) && now.Sub(st.LastFailure) > t.config.ResetAfter {
+		delete(t.states, key)
+		return nil
+	}
+	return st
+}
+
+// Check indique si une tentative est autorisée pour key, et sinon combien de temps attendre.
+// Une tentative autorisée est réservée : elle compte comme un échec possible tant que
+// Failure, Success ou Release n'a pas été appelé. Les tentatives parallèles ne sont
+// permises que dans la limite des échecs encore tolérés, une seule au-delà.
+func (t *Throttle) Check(key string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	st := t.state(key, now)
+	if st == nil {
+		st = &ThrottleStatus{Key: key}
+		t.states[key] = st
+	}
+	if now.Before(st.BlockedUntil) {
+		return st.BlockedUntil.Sub(now), false
+	}
+	if st.pending > 0 && st.Failures+st.pending >= t.config.FreeAttempts {
+		// le résultat des tentatives en cours décidera du prochain délai
+		return t.config.BaseDelay, false
+	}
+	st.pending++
+	st.reservedAt = now
+	return 0, true
+}
+
+// Release annule la réservation d'une tentative dont le résultat est inconnu (erreur serveur)
+func (t *Throttle) Release(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if st := t.state(key, time.Now()); st != nil && st.pending > 0 {
+		st.pending--
+	}
+}
+
+// Failure enregistre un échec pour key et retourne son nouvel état
+func (t *Throttle) Failure(key string) ThrottleStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	// nettoyage des clés inactives pour que la table ne grossisse pas indéfiniment
+	if len(t.states) > maxTrackedKeys {
+		for k := range t.states {
+			t.state(k, now)
+		}
+	}
+
+	st := t.state(key, now)
+	if st == nil {
+		st = &ThrottleStatus{Key: key}
+		t.states[key] = st
+	}
+	if st.pending > 0 {
+		st.pending--
+	}
+	st.Failures++
+	st.LastFailure = now
+
+	switch {
+	case st.Failures >= t.config.MaxFailures:
+		st.Locked = true
+		st.BlockedUntil = now.Add(t.config.LockoutDuration)
+	case st.Failures > t.config.FreeAttempts:
+		delay := t.config.BaseDelay << uint(st.Failures-t.config.FreeAttempts-1)
+		if delay <= 0 || delay > t.config.MaxDelay {
+			delay = t.config.MaxDelay
+		}
+		st.BlockedUntil = now.Add(delay)
+	}
+	return *st
+}
+
+// Success efface les échecs et les réservations de key
+func (t *Throttle) Success(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.states, key)
+}
+
+// Status retourne les clés ayant des échecs en cours, les plus récents d'abord
+func (t *Throttle) Status() []ThrottleStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	result := []ThrottleStatus{}
+	for key := range t.states {
+		if st := t.state(key, now); st != nil && st.Failures > 0 {
+			status := *st
+			// un verrouillage terminé n'est plus signalé
+			status.Locked = st.Locked && now.Before(st.BlockedUntil)
+			result = append(result, status)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].LastFailure.After(result[j].LastFailure) })
+	return result
+}
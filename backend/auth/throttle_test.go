@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func testThrottle() *Throttle {
+	return NewThrottle(ThrottleConfig{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		MaxFailures:     5,
+		LockoutDuration: time.Hour,
+		ResetAfter:      time.Hour,
+	})
+}
+
+func TestThrottleBackoff(t *testing.T) {
+	throttle := testThrottle()
+	for i := 1; i <= 5; i++ {
+		if _, ok := throttle.Check("alice"); !ok {
+			t.Fatalf("tentative %d refusée", i)
+		}
+		status := throttle.Failure("alice")
+		if blocked := status.BlockedUntil.After(time.Now()); blocked != (i > 3) {
+			t.Errorf("échec %d : bloqué %v", i, blocked)
+		}
+		// on simule la fin du délai, sauf pour le verrouillage
+		if i < 5 {
+			throttle.states["alice"].BlockedUntil = time.Time{}
+		}
+	}
+	if wait, ok := throttle.Check("alice"); ok || wait < 59*time.Minute {
+		t.Errorf("compte verrouillé : attente %v, autorisé %v", wait, ok)
+	}
+	if status := throttle.Status(); len(status) != 1 || !status[0].Locked {
+		t.Errorf("état : %+v", status)
+	}
+
+	throttle.Success("alice")
+	if _, ok := throttle.Check("alice"); !ok {
+		t.Error("tentative refusée après un succès")
+	}
+}
+
+// des tentatives lancées en parallèle ne dépassent pas les échecs tolérés
+func TestThrottleParallelAttempts(t *testing.T) {
+	throttle := testThrottle()
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	start := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, ok := throttle.Check("alice"); ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if allowed != 3 {
+		t.Fatalf("%d tentatives parallèles autorisées, attendu 3", allowed)
+	}
+	if status := throttle.Status(); len(status) != 0 {
+		t.Errorf("des tentatives en cours sont signalées comme des échecs : %+v", status)
+	}
+
+	// au-delà des échecs tolérés, une seule tentative à la fois
+	for i := 0; i < 3; i++ {
+		throttle.Failure("alice")
+	}
+	if _, ok := throttle.Check("alice"); !ok {
+		t.Fatal("tentative refusée après la fin du délai")
+	}
+	if _, ok := throttle.Check("alice"); ok {
+		t.Error("seconde tentative parallèle autorisée au-delà des échecs tolérés")
+	}
+	throttle.Release("alice")
+	if _, ok := throttle.Check("alice"); !ok {
+		t.Error("tentative refusée après l'annulation de la réservation")
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"quizmaster/config"
+	"time"
+)
+
+// NewToken génère un token aléatoire de 256 bits encodé en base64 (URL)
+func NewToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken retourne l'empreinte SHA-256 du token, seule valeur conservée en base
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AccessTokenTTL est la durée de validité d'un token d'accès (ACCESS_TOKEN_TTL, 24h par défaut)
+func AccessTokenTTL() time.Duration {
+	return config.Duration("ACCESS_TOKEN_TTL", 24*time.Hour)
+}
+
+// RefreshTokenTTL est la durée de validité d'un token de rafraîchissement (REFRESH_TOKEN_TTL, 30 jours par défaut)
+func RefreshTokenTTL() time.Duration {
+	return config.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lecture des variables d'environnement avec valeur par défaut.
+// Une valeur invalide est signalée dans les logs et remplacée par la valeur par défaut.
+
+// String retourne la variable name, ou def si elle est vide
+func String(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// Int retourne la variable name convertie en entier
+func Int(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Valeur invalide pour %s (%q), utilisation de %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+// Float retourne la variable name convertie en nombre décimal
+func Float(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Valeur invalide pour %s (%q), utilisation de %g", name, value, def)
+		return def
+	}
+	return f
+}
+
+// Duration retourne la variable name convertie en durée (ex : "30s", "15m")
+func Duration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Valeur invalide pour %s (%q), utilisation de %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// Bool retourne la variable name convertie en booléen
+func Bool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Valeur invalide pour %s (%q), utilisation de %t", name, value, def)
+		return def
+	}
+	return b
+}
+
+// List retourne la variable name découpée selon les virgules
+func List(name string, def []string) []string {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"quizmaster/auth"
+	"quizmaster/config"
+	"quizmaster/model"
+	"time"
+)
+
+// erreurs d'authentification par token
+var (
+	ErrInvalidToken = errors.New("token invalide")
+	ErrExpiredToken = errors.New("token expiré")
+)
+
+// structure de réponse pour la connexion
+type LoginResponse struct {
+	Status       int        `json:"status"`
+	Message      string     `json:"message"`
+	UserID       string     `json:"userID"`
+	Token        string     `json:"token,omitempty"`
+	RefreshToken string     `json:"refreshToken,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
+
+// vérifie les identifiants de l'utilisateur et ouvre une nouvelle session
+func Login(ctx context.Context, store Store, username, password string) (LoginResponse, error) {
+	// 1. Récupérer l'utilisateur par son username uniquement
+	user, err := store.GetUserByName(ctx, username)
+	if err != nil {
+		if err == ErrNotFound {
+			return LoginResponse{Status: 401, Message: "Identifiants invalides"}, nil
+		}
+		return LoginResponse{}, err
+	}
+
+	// 2. Comparer le mot de passe fourni avec celui haché en base
+	if !auth.CheckPassword(user.Password, password) {
+		return LoginResponse{Status: 401, Message: "Identifiants invalides"}, nil
+	}
+
+	if user.Banned {
+		return LoginResponse{Status: 403, Message: "Compte banni"}, nil
+	}
+
+	log.Printf("Authentification réussie pour l'utilisateur %s\n", user.Username)
+
+	// 3. Création d'une session, les autres sessions de l'utilisateur restent valides
+	response, err := openSession(ctx, store, user.ID)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	response.Message = "Authentification réussie"
+	return response, nil
+}
+
+// IsAdminUsername indique si username fait partie des administrateurs configurés (ADMIN_USERNAMES)
+func IsAdminUsername(username string) bool {
+	for _, name := range config.List("ADMIN_USERNAMES", nil) {
+		if name == username {
+			return true
+		}
+	}
+	return false
+}
+
+// EnsureAdmins donne le rôle admin aux comptes existants listés dans ADMIN_USERNAMES
+func EnsureAdmins(ctx context.Context, store Store) error {
+	for _, name := range config.List("ADMIN_USERNAMES", nil) {
+		user, err := store.GetUserByName(ctx, name)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if user.Role != model.RoleAdmin {
+			log.Printf("Attribution du rôle admin à %s", name)
+			if err = store.SetUserRole(ctx, user.ID, model.RoleAdmin); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// génère une paire de tokens (accès, rafraîchissement) pour la session
+func newSessionTokens(session *model.Session) (string, string, error) {
+	accessToken, err := auth.NewToken()
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := auth.NewToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	session.AccessHash = auth.HashToken(accessToken)
+	session.RefreshHash = auth.HashToken(refreshToken)
+	session.ExpiresAt = now.Add(auth.AccessTokenTTL())
+	session.RefreshExpiresAt = now.Add(auth.RefreshTokenTTL())
+	return accessToken, refreshToken, nil
+}
+
+// crée une session pour l'utilisateur et retourne ses tokens
+func openSession(ctx context.Context, store Store, userID string) (LoginResponse, error) {
+	session := model.Session{UserID: userID, CreatedAt: time.Now()}
+	accessToken, refreshToken, err := newSessionTokens(&session)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	if _, err = store.CreateSession(ctx, session); err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{Status: 200, UserID: userID, Token: accessToken, RefreshToken: refreshToken, ExpiresAt: &session.ExpiresAt}, nil
+}
+
+// RefreshSession échange un token de rafraîchissement contre une nouvelle paire de tokens.
+// L'ancien token de rafraîchissement devient inutilisable.
+func RefreshSession(ctx context.Context, store Store, refreshToken string) (LoginResponse, error) {
+	oldHash := auth.HashToken(refreshToken)
+	session, err := store.GetSessionByRefreshHash(ctx, oldHash)
+	if err != nil {
+		if err == ErrNotFound {
+			return LoginResponse{}, ErrInvalidToken
+		}
+		return LoginResponse{}, err
+	}
+	if time.Now().After(session.RefreshExpiresAt) {
+		store.DeleteSession(ctx, session.ID)
+		return LoginResponse{}, ErrExpiredToken
+	}
+
+	accessToken, newRefreshToken, err := newSessionTokens(&session)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if err = store.RotateSession(ctx, oldHash, session); err != nil {
+		if err == ErrNotFound {
+			// le token vient d'être utilisé par une autre requête
+			return LoginResponse{}, ErrInvalidToken
+		}
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		Status:       200,
+		Message:      "Session renouvelée",
+		UserID:       session.UserID,
+		Token:        accessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresAt:    &session.ExpiresAt,
+	}, nil
+}
+
+// Authenticate retourne l'utilisateur et la session associés à un token d'accès valide
+func Authenticate(ctx context.Context, store Store, token string) (model.User, model.Session, error) {
+	session, err := store.GetSessionByAccessHash(ctx, auth.HashToken(token))
+	if err != nil {
+		if err == ErrNotFound {
+			return model.User{}, session, ErrInvalidToken
+		}
+		return model.User{}, session, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return model.User{}, session, ErrExpiredToken
+	}
+
+	user, err := store.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		if err == ErrNotFound {
+			// l'utilisateur a été supprimé depuis l'ouverture de la session
+			store.DeleteSession(ctx, session.ID)
+			return model.User{}, session, ErrInvalidToken
+		}
+		return model.User{}, session, err
+	}
+	return user, session, nil
+}
+
+// GetUserByToken recherche l'utilisateur associé à un token d'accès valide
+func GetUserByToken(ctx context.Context, store Store, token string) (model.User, error) {
+	user, _, err := Authenticate(ctx, store, token)
+	return user, err
+}
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"quizmaster/config"
+	"quizmaster/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// erreurs des tirages
+var (
+	// nombre de tirages qui n'est pas proposé par la bannière
+	ErrInvalidPull = errors.New("nombre de tirages invalide")
+	// bannière inexistante, pas encore ouverte ou terminée
+	ErrBannerUnavailable = errors.New("bannière indisponible")
+)
+
+// identifiant de la bannière par défaut, utilisée quand aucune bannière n'est précisée
+const DefaultBannerID = "standard"
+
+// tirages proposés par défaut, nombre d'antisèches : prix
+var defaultPullPrices = []string{"1:100", "10:900"}
+
+// PullPrices retourne le prix de chaque nombre de tirages de la bannière par défaut,
+// configurable avec GACHA_PULLS (ex : "1:100,10:900")
+func PullPrices() map[int]int {
+	prices := make(map[int]int)
+	for _, item := range config.List("GACHA_PULLS", defaultPullPrices) {
+		quantity, price, _ := strings.Cut(item, ":")
+		n, errQuantity := strconv.Atoi(strings.TrimSpace(quantity))
+		p, errPrice := strconv.Atoi(strings.TrimSpace(price))
+		if errQuantity != nil || errPrice != nil || n < 1 || p < 0 {
+			log.Printf("Tirage invalide dans GACHA_PULLS : %q", item)
+			continue
+		}
+		prices[n] = p
+	}
+	return prices
+}
+
+// DefaultBanner est la bannière permanente proposée tant qu'aucune bannière "standard"
+// n'est enregistrée en base
+func DefaultBanner() model.Banner {
+	banner := model.Banner{
+		ID:   DefaultBannerID,
+		Name: "Bannière standard",
+		Weights: []model.RarityWeight{
+			{Rarity: 3, Weight: 80},
+			{Rarity: 4, Weight: 10},
+			{Rarity: 5, Weight: 5},
+			{Rarity: 6, Weight: 5},
+		},
+	}
+	prices := PullPrices()
+	for _, quantity := range sortedKeys(prices) {
+		banner.Pulls = append(banner.Pulls, model.PullOption{Quantity: quantity, Price: prices[quantity]})
+	}
+	return banner
+}
+
+// BannerActive indique si la bannière est ouverte à la date now
+func BannerActive(banner model.Banner, now time.Time) bool {
+	if banner.StartsAt != nil && now.Before(*banner.StartsAt) {
+		return false
+	}
+	if banner.EndsAt != nil && !now.Before(*banner.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// Banners retourne toutes les bannières : celles enregistrées et la bannière par défaut
+// si elle n'est pas remplacée en base
+func Banners(ctx context.Context, store Store) ([]model.Banner, error) {
+	banners, err := store.ListBanners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, banner := range banners {
+		if banner.ID == DefaultBannerID {
+			return banners, nil
+		}
+	}
+	return append([]model.Banner{DefaultBanner()}, banners...), nil
+}
+
+// retourne la bannière bannerID si elle est ouverte
+func activeBanner(ctx context.Context, store Store, bannerID string) (model.Banner, error) {
+	banner, err := store.GetBanner(ctx, bannerID)
+	if err == ErrNotFound && bannerID == DefaultBannerID {
+		banner, err = DefaultBanner(), nil
+	}
+	if err == ErrNotFound {
+		return banner, ErrBannerUnavailable
+	}
+	if err != nil {
+		return banner, err
+	}
+	if !BannerActive(banner, time.Now()) {
+		return banner, ErrBannerUnavailable
+	}
+	return banner, nil
+}
+
+// tire une rareté selon les poids de la bannière
+func drawRarity(weights []model.RarityWeight) int {
+	total := 0.0
+	for _, weight := range weights {
+		total += weight.Weight
+	}
+	value := rand.Float64() * total
+	for _, weight := range weights {
+		if value < weight.Weight {
+			return weight.Rarity
+		}
+		value -= weight.Weight
+	}
+	// arrondi des flottants : la dernière rareté
+	return weights[len(weights)-1].Rarity
+}
+
+// effectue number_pull tirages d'antisèches sur la bannière bannerID et retourne les raretés obtenues.
+// Le prix est débité et les antisèches ajoutées en une seule opération, si le solde suffit.
+func GetCheatSheet(ctx context.Context, store Store, userName string, bannerID string, number_pull int) ([]int, error) {
+	banner, err := activeBanner(ctx, store, bannerID)
+	if err != nil {
+		return nil, err
+	}
+	price := -1
+	for _, pull := range banner.Pulls {
+		if pull.Quantity == number_pull {
+			price = pull.Price
+		}
+	}
+	if price < 0 || len(banner.Weights) == 0 {
+		return nil, ErrInvalidPull
+	}
+
+	var result []int
+	pulled := make(map[int]int)
+	for i := 0; i < number_pull; i++ {
+		rarity := drawRarity(banner.Weights)
+		result = append(result, rarity)
+		pulled[rarity]++
+	}
+
+	user, err := store.PullCheatSheets(ctx, userName, banner.ID, price, pulled)
+	if err == ErrInsufficientBalance {
+		log.Printf("❌ Pas assez de pièces pour %s : %d nécessaires\n", userName, price)
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("❌ Erreur mise à jour de l'inventaire: %v\n", err)
+		return nil, err
+	}
+
+	log.Printf("✅ Inventaire de %s mis à jour: %v, %d pièces", userName, user.Inventory, user.Coins)
+	return result, nil
+}
+
+// mouvement du journal d'un tirage : la bannière, le prix et les antisèches obtenues par rareté
+func pullLedgerEntry(bannerID string, price int, pulled map[int]int) model.LedgerEntry {
+	entry := model.LedgerEntry{Kind: model.LedgerGachaPull, Reference: bannerID, Coins: -price}
+	for _, rarity := range sortedKeys(pulled) {
+		entry.Items = append(entry.Items, model.ItemMovement{Rarity: rarity, Quantity: pulled[rarity]})
+	}
+	return entry
+}
+
+// clés de la table (raretés, nombres de tirages), dans l'ordre croissant
+func sortedKeys(pulled map[int]int) []int {
+	rarities := make([]int, 0, len(pulled))
+	for rarity := range pulled {
+		rarities = append(rarities, rarity)
+	}
+	sort.Ints(rarities)
+	return rarities
+}
+
+// utilise une antisèche sur la question courante du quiz et retourne les mauvaises réponses révélées
+func UseCheatSheet(ctx context.Context, store Store, quiz model.Quiz, rarity int) ([]string, error) {
+	var hints int = 0
+	if rarity == 5 {
+		hints = 3
+	}
+	if rarity == 4 {
+		hints = 2
+	}
+	if rarity == 3 {
+		hints = 1
+	}
+
+	var allHints []string
+	// les questions à choix multiples ont plusieurs bonnes réponses, les questions
+	// à réponse libre ou numérique n'ont pas de réponses proposées
+	currentQuestion := quiz.Questions[quiz.Number_question]
+	correct := map[string]bool{currentQuestion.ResponseCorrect: true}
+	for _, response := range currentQuestion.ResponsesCorrect {
+		correct[response] = true
+	}
+	for _, response := range currentQuestion.Responses {
+		if !correct[response] {
+			allHints = append(allHints, response)
+		}
+	}
+
+	// Mélanger les réponses
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r.Shuffle(len(allHints), func(i, j int) { allHints[i], allHints[j] = allHints[j], allHints[i] })
+
+	// Sélectionner le nombre d'indices requis
+	var result []string
+	for i := 0; i < hints && i < len(allHints); i++ {
+		result = append(result, allHints[i])
+	}
+
+	//Mettre a jour les cheatsheets de l'user
+	err := store.ConsumeCheatSheet(ctx, quiz.Username, rarity, quiz.ID)
+	if err != nil {
+		log.Printf("❌ Erreur lors de la mise à jour de l'inventaire de l'utilisateur : %v\n", err)
+		return nil, err
+	}
+
+	return result, nil
+}
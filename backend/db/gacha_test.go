@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryStorePullCheatSheets(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		coins     int
+		price     int
+		pulled    map[int]int
+		wantErr   error
+		wantCoins int
+		wantItems map[int]int
+	}{
+		{"rareté déjà possédée", 100, 100, map[int]int{3: 2}, nil, 0, map[int]int{3: 3}},
+		{"nouvelle rareté", 900, 900, map[int]int{3: 8, 5: 2}, nil, 0, map[int]int{3: 9, 5: 2}},
+		{"solde insuffisant", 99, 100, map[int]int{6: 1}, ErrInsufficientBalance, 99, map[int]int{3: 1, 6: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			user := newTestUser(t, store, "alice", tt.coins)
+
+			_, err := store.PullCheatSheets(ctx, "alice", DefaultBannerID, tt.price, tt.pulled)
+			if err != tt.wantErr {
+				t.Fatalf("PullCheatSheets = %v, attendu %v", err, tt.wantErr)
+			}
+			got, _ := store.GetUserByID(ctx, user.ID)
+			if got.Coins != tt.wantCoins {
+				t.Errorf("%d pièces, attendu %d", got.Coins, tt.wantCoins)
+			}
+			for rarity, quantity := range tt.wantItems {
+				if actual := inventoryQuantity(got.Inventory, rarity); actual != quantity {
+					t.Errorf("%d antisèches de rareté %d, attendu %d", actual, rarity, quantity)
+				}
+			}
+			// un tirage refusé ne modifie pas l'inventaire
+			if err != nil && len(got.Inventory) != len(user.Inventory) {
+				t.Errorf("inventaire modifié par un tirage refusé : %+v", got.Inventory)
+			}
+			assertLedgerConsistent(t, store, user.ID)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"fmt"
+	"quizmaster/model"
+	"sort"
+	"time"
+)
+
+// complète un mouvement avec l'état de l'utilisateur juste après : numéro et soldes
+func newLedgerEntry(user model.User, entry model.LedgerEntry) model.LedgerEntry {
+	entry.UserID = user.ID
+	entry.Seq = user.LedgerSeq
+	entry.CoinsAfter = user.Coins
+	entry.Items = append([]model.ItemMovement(nil), entry.Items...)
+	for i := range entry.Items {
+		entry.Items[i].QuantityAfter = inventoryQuantity(user.Inventory, entry.Items[i].Rarity)
+	}
+	entry.CreatedAt = time.Now()
+	return entry
+}
+
+// nombre d'antisèches de la rareté dans l'inventaire
+func inventoryQuantity(inventory []model.CheatSheet, rarity int) int {
+	for _, item := range inventory {
+		if item.Rarity == rarity {
+			return item.Quantity
+		}
+	}
+	return 0
+}
+
+// CheckLedger rejoue le journal d'un utilisateur, trié par numéro, et retourne les incohérences :
+// mouvements manquants, solde après un mouvement qui ne suit pas le précédent, soldes de
+// l'utilisateur différents de ceux du dernier mouvement. Les soldes d'avant le premier mouvement
+// (utilisateurs créés avant le journal) sont déduits de celui-ci.
+func CheckLedger(user model.User, entries []model.LedgerEntry) []string {
+	issues := []string{}
+	seq := 0
+	var coins int
+	items := make(map[int]int)
+
+	for i, entry := range entries {
+		if entry.Seq != seq+1 {
+			issues = append(issues, fmt.Sprintf("mouvements %d à %d manquants", seq+1, entry.Seq-1))
+		}
+		seq = entry.Seq
+
+		if i > 0 && coins+entry.Coins != entry.CoinsAfter {
+			issues = append(issues, fmt.Sprintf("mouvement %d : %d pièces attendues, %d enregistrées", entry.Seq, coins+entry.Coins, entry.CoinsAfter))
+		}
+		coins = entry.CoinsAfter
+
+		for _, item := range entry.Items {
+			if quantity, ok := items[item.Rarity]; ok && quantity+item.Quantity != item.QuantityAfter {
+				issues = append(issues, fmt.Sprintf("mouvement %d : %d antisèches de rareté %d attendues, %d enregistrées", entry.Seq, quantity+item.Quantity, item.Rarity, item.QuantityAfter))
+			}
+			items[item.Rarity] = item.QuantityAfter
+		}
+	}
+
+	if user.LedgerSeq > seq {
+		issues = append(issues, fmt.Sprintf("mouvements %d à %d manquants", seq+1, user.LedgerSeq))
+	}
+	if len(entries) == 0 {
+		return issues
+	}
+	if user.Coins != coins {
+		issues = append(issues, fmt.Sprintf("%d pièces selon le journal, %d sur le compte", coins, user.Coins))
+	}
+	rarities := make([]int, 0, len(items))
+	for rarity := range items {
+		rarities = append(rarities, rarity)
+	}
+	sort.Ints(rarities)
+	for _, rarity := range rarities {
+		quantity := items[rarity]
+		if actual := inventoryQuantity(user.Inventory, rarity); actual != quantity {
+			issues = append(issues, fmt.Sprintf("%d antisèches de rareté %d selon le journal, %d sur le compte", quantity, rarity, actual))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestCheckLedger(t *testing.T) {
+	entries := []model.LedgerEntry{
+		{Seq: 1, Coins: 100, CoinsAfter: 1100},
+		{Seq: 2, Coins: -100, CoinsAfter: 1000, Items: []model.ItemMovement{{Rarity: 3, Quantity: 1, QuantityAfter: 2}}},
+		{Seq: 3, CoinsAfter: 1000, Items: []model.ItemMovement{{Rarity: 3, Quantity: -1, QuantityAfter: 1}}},
+	}
+	user := model.User{Coins: 1000, Inventory: []model.CheatSheet{{Rarity: 3, Quantity: 1}}, LedgerSeq: 3}
+
+	tests := []struct {
+		name       string
+		user       func(model.User) model.User
+		entries    func([]model.LedgerEntry) []model.LedgerEntry
+		wantIssues int
+	}{
+		{"journal cohérent", nil, nil, 0},
+		{"aucun mouvement", func(u model.User) model.User { u.LedgerSeq = 0; return u },
+			func([]model.LedgerEntry) []model.LedgerEntry { return nil }, 0},
+		{"mouvement manquant", nil,
+			func(e []model.LedgerEntry) []model.LedgerEntry { return []model.LedgerEntry{e[0], e[2]} }, 2},
+		{"dernier mouvement manquant", nil,
+			func(e []model.LedgerEntry) []model.LedgerEntry { return e[:2] }, 2},
+		{"pièces modifiées hors du journal", func(u model.User) model.User { u.Coins += 500; return u }, nil, 1},
+		{"antisèches modifiées hors du journal", func(u model.User) model.User {
+			u.Inventory = []model.CheatSheet{{Rarity: 3, Quantity: 5}}
+			return u
+		}, nil, 1},
+		{"solde après mouvement incohérent", nil, func(e []model.LedgerEntry) []model.LedgerEntry {
+			e = append([]model.LedgerEntry(nil), e...)
+			e[1].CoinsAfter = 900
+			return e
+		}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, e := user, entries
+			if tt.user != nil {
+				u = tt.user(u)
+			}
+			if tt.entries != nil {
+				e = tt.entries(e)
+			}
+			if issues := CheckLedger(u, e); len(issues) != tt.wantIssues {
+				t.Errorf("%d incohérences, attendu %d : %v", len(issues), tt.wantIssues, issues)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreBalanceMovements(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	user := newTestUser(t, store, "alice", 10)
+
+	tests := []struct {
+		name    string
+		move    func() error
+		wantErr error
+	}{
+		{"crédit de pièces", func() error { _, err := store.AdjustCoins(ctx, user.ID, 5, "test"); return err }, nil},
+		{"débit supérieur au solde", func() error { _, err := store.AdjustCoins(ctx, user.ID, -16, "test"); return err }, ErrInsufficientBalance},
+		{"débit du solde", func() error { _, err := store.AdjustCoins(ctx, user.ID, -15, "test"); return err }, nil},
+		{"utilisation d'une antisèche", func() error { return store.ConsumeCheatSheet(ctx, "alice", 3, "quiz1") }, nil},
+		{"antisèche épuisée", func() error { return store.ConsumeCheatSheet(ctx, "alice", 3, "quiz1") }, ErrInsufficientBalance},
+		{"ajout d'une rareté", func() error { _, err := store.AdjustInventory(ctx, user.ID, 4, 2, "test"); return err }, nil},
+		{"retrait supérieur à l'inventaire", func() error { _, err := store.AdjustInventory(ctx, user.ID, 4, -3, "test"); return err }, ErrInsufficientBalance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.move(); err != tt.wantErr {
+				t.Errorf("erreur %v, attendu %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	got, _ := store.GetUserByID(ctx, user.ID)
+	if got.Coins != 0 || inventoryQuantity(got.Inventory, 3) != 0 || inventoryQuantity(got.Inventory, 4) != 2 {
+		t.Errorf("soldes finaux : %d pièces, inventaire %+v", got.Coins, got.Inventory)
+	}
+	if got.LedgerSeq != 4 {
+		t.Errorf("%d mouvements, attendu 4", got.LedgerSeq)
+	}
+	assertLedgerConsistent(t, store, user.ID)
+}
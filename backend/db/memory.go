@@ -0,0 +1,1036 @@
+package db
+
+import (
+	"context"
+	"math/rand"
+	"quizmaster/model"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore implémente Store entièrement en mémoire.
+// Les documents sont copiés à l'entrée et à la sortie pour reproduire le comportement de MongoDB.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	users      map[string]model.User
+	sessions   map[string]model.Session
+	resets     map[string]model.PasswordReset
+	categories []model.Category
+	quizzes    map[string]model.Quiz
+	audit      []model.AuditEntry
+	ledger     []model.LedgerEntry
+	banners    map[string]model.Banner
+	pool       []model.PoolQuestion
+	// explications par empreinte de question
+	explanations map[string]model.Explanation
+}
+
+// NewMemoryStore crée un Store vide
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:    make(map[string]model.User),
+		sessions: make(map[string]model.Session),
+		resets:   make(map[string]model.PasswordReset),
+		quizzes:  make(map[string]model.Quiz),
+		banners:  make(map[string]model.Banner),
+
+		explanations: make(map[string]model.Explanation),
+	}
+}
+
+// Close ne fait rien, il n'y a pas de connexion à fermer
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// ================== Copies ==================
+
+func copyUser(user model.User) model.User {
+	user.Inventory = append([]model.CheatSheet(nil), user.Inventory...)
+	return user
+}
+
+func copyLedgerEntry(entry model.LedgerEntry) model.LedgerEntry {
+	entry.Items = append([]model.ItemMovement(nil), entry.Items...)
+	return entry
+}
+
+func copyBanner(banner model.Banner) model.Banner {
+	banner.Weights = append([]model.RarityWeight(nil), banner.Weights...)
+	banner.Pulls = append([]model.PullOption(nil), banner.Pulls...)
+	banner.Featured = append([]int(nil), banner.Featured...)
+	return banner
+}
+
+func copyQuestions(questions []model.Question) []model.Question {
+	if questions == nil {
+		return nil
+	}
+	result := make([]model.Question, len(questions))
+	for i, question := range questions {
+		question.Responses = append([]string(nil), question.Responses...)
+		question.ResponsesCorrect = append([]string(nil), question.ResponsesCorrect...)
+		question.Aliases = append([]string(nil), question.Aliases...)
+		result[i] = question
+	}
+	return result
+}
+
+func copyCategory(category model.Category) model.Category {
+	category.Questions = copyQuestions(category.Questions)
+	return category
+}
+
+func copyQuiz(quiz model.Quiz) model.Quiz {
+	quiz.Questions = copyQuestions(quiz.Questions)
+	quiz.Answers = append([]model.AnswerRecord(nil), quiz.Answers...)
+	for i := range quiz.Answers {
+		quiz.Answers[i].Answers = append([]string(nil), quiz.Answers[i].Answers...)
+	}
+	return quiz
+}
+
+// ================== Fonctions pour User ==================
+
+func (s *MemoryStore) InsertUser(ctx context.Context, user model.User) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.ID = primitive.NewObjectID().Hex()
+	s.users[user.ID] = copyUser(user)
+	return user.ID, nil
+}
+
+func (s *MemoryStore) UsernameExists(ctx context.Context, username string) (bool, error) {
+	_, err := s.GetUserByName(ctx, username)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *MemoryStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []model.User
+	for _, user := range s.users {
+		users = append(users, copyUser(user))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *MemoryStore) GetUserByID(ctx context.Context, userID string) (model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+// retourne le premier utilisateur vérifiant match, à appeler avec le verrou
+func (s *MemoryStore) findUser(match func(model.User) bool) (model.User, bool) {
+	for _, user := range s.users {
+		if match(user) {
+			return user, true
+		}
+	}
+	return model.User{}, false
+}
+
+func (s *MemoryStore) GetUserByName(ctx context.Context, username string) (model.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.findUser(func(u model.User) bool { return u.Username == username })
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (s *MemoryStore) GetTopPlayers(ctx context.Context, limit int) ([]model.User, error) {
+	users, _ := s.GetAllUsers(ctx)
+	sort.SliceStable(users, func(i, j int) bool { return users[i].Experience > users[j].Experience })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// applique update à l'utilisateur userID
+func (s *MemoryStore) updateUser(userID string, update func(*model.User)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	update(&user)
+	s.users[userID] = user
+	return nil
+}
+
+func (s *MemoryStore) SetUserPicture(ctx context.Context, userID string, picture string) error {
+	return s.updateUser(userID, func(u *model.User) { u.Picture = picture })
+}
+
+func (s *MemoryStore) DeleteUser(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.users, userID)
+	return nil
+}
+
+func (s *MemoryStore) UpdateUserUsername(ctx context.Context, userID string, newUsername string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.Username == newUsername {
+		return ErrNoChange
+	}
+	user.Username = newUsername
+	s.users[userID] = user
+	return nil
+}
+
+func (s *MemoryStore) UpdateUserPassword(ctx context.Context, userID string, newPassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok || user.Password == newPassword {
+		return ErrNoChange
+	}
+	user.Password = newPassword
+	s.users[userID] = user
+	return nil
+}
+
+func (s *MemoryStore) UpdateUser(ctx context.Context, user model.User) error {
+	return s.updateUser(user.ID, func(u *model.User) {
+		// les pièces, l'inventaire et les statistiques changent à part, comme avec MongoDB
+		u.Experience = user.Experience
+	})
+}
+
+func (s *MemoryStore) SetUserRole(ctx context.Context, userID string, role string) error {
+	return s.updateUser(userID, func(u *model.User) { u.Role = role })
+}
+
+func (s *MemoryStore) SetUserBanned(ctx context.Context, userID string, banned bool) error {
+	return s.updateUser(userID, func(u *model.User) { u.Banned = banned })
+}
+
+func (s *MemoryStore) SetUserEmail(ctx context.Context, userID string, email string) error {
+	return s.updateUser(userID, func(u *model.User) { u.Email = email })
+}
+
+func (s *MemoryStore) SetUserOpenTDBToken(ctx context.Context, userID string, token string) error {
+	return s.updateUser(userID, func(u *model.User) { u.OpenTDBToken = token })
+}
+
+// ================== Fonctions pour les sessions ==================
+
+func (s *MemoryStore) CreateSession(ctx context.Context, session model.Session) (model.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.ID = primitive.NewObjectID().Hex()
+	s.sessions[session.ID] = session
+	return session, nil
+}
+
+// retourne la session vérifiant match, à appeler avec le verrou
+func (s *MemoryStore) findSession(match func(model.Session) bool) (model.Session, bool) {
+	for _, session := range s.sessions {
+		if match(session) {
+			return session, true
+		}
+	}
+	return model.Session{}, false
+}
+
+func (s *MemoryStore) GetSessionByAccessHash(ctx context.Context, accessHash string) (model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.findSession(func(se model.Session) bool { return se.AccessHash == accessHash })
+	if !ok {
+		return model.Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+func (s *MemoryStore) GetSessionByRefreshHash(ctx context.Context, refreshHash string) (model.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, ok := s.findSession(func(se model.Session) bool { return se.RefreshHash == refreshHash })
+	if !ok {
+		return model.Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+func (s *MemoryStore) RotateSession(ctx context.Context, oldRefreshHash string, next model.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[next.ID]
+	if !ok || session.RefreshHash != oldRefreshHash {
+		return ErrNotFound
+	}
+	session.AccessHash = next.AccessHash
+	session.RefreshHash = next.RefreshHash
+	session.ExpiresAt = next.ExpiresAt
+	session.RefreshExpiresAt = next.RefreshExpiresAt
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *MemoryStore) DeleteSession(ctx context.Context, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sessionID)
+	return nil
+}
+
+func (s *MemoryStore) DeleteUserSessions(ctx context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) DeleteOtherSessions(ctx context.Context, userID string, keepSessionID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for id, session := range s.sessions {
+		if session.UserID == userID && id != keepSessionID {
+			delete(s.sessions, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ================== Fonctions pour la réinitialisation de mot de passe ==================
+
+func (s *MemoryStore) CreatePasswordReset(ctx context.Context, reset model.PasswordReset) (model.PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reset.ID = primitive.NewObjectID().Hex()
+	s.resets[reset.ID] = reset
+	return reset, nil
+}
+
+func (s *MemoryStore) GetPasswordResetByHash(ctx context.Context, tokenHash string) (model.PasswordReset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, reset := range s.resets {
+		if reset.TokenHash == tokenHash {
+			return reset, nil
+		}
+	}
+	return model.PasswordReset{}, ErrNotFound
+}
+
+func (s *MemoryStore) UsePasswordReset(ctx context.Context, resetID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reset, ok := s.resets[resetID]
+	if !ok || reset.UsedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	reset.UsedAt = &now
+	s.resets[resetID] = reset
+	return nil
+}
+
+func (s *MemoryStore) DeleteUserPasswordResets(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, reset := range s.resets {
+		if reset.UserID == userID {
+			delete(s.resets, id)
+		}
+	}
+	return nil
+}
+
+// ================== Fonctions pour l'inventaire ==================
+
+// enregistre l'utilisateur modifié et inscrit le mouvement au journal, à appeler avec le verrou
+func (s *MemoryStore) saveWithLedger(user model.User, entry model.LedgerEntry) {
+	user.LedgerSeq++
+	s.users[user.ID] = user
+	entry = newLedgerEntry(user, entry)
+	entry.ID = primitive.NewObjectID().Hex()
+	s.ledger = append(s.ledger, entry)
+}
+
+func (s *MemoryStore) PullCheatSheets(ctx context.Context, username string, bannerID string, price int, pulled map[int]int) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.findUser(func(u model.User) bool { return u.Username == username })
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	if user.Coins < price {
+		return model.User{}, ErrInsufficientBalance
+	}
+	user = copyUser(user)
+	user.Coins -= price
+	for _, rarity := range sortedKeys(pulled) {
+		i := 0
+		for i < len(user.Inventory) && user.Inventory[i].Rarity != rarity {
+			i++
+		}
+		if i == len(user.Inventory) {
+			user.Inventory = append(user.Inventory, model.CheatSheet{Rarity: rarity})
+		}
+		user.Inventory[i].Quantity += pulled[rarity]
+	}
+	s.saveWithLedger(user, pullLedgerEntry(bannerID, price, pulled))
+	return copyUser(s.users[user.ID]), nil
+}
+
+func (s *MemoryStore) ConsumeCheatSheet(ctx context.Context, username string, rarity int, quizID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.findUser(func(u model.User) bool { return u.Username == username })
+	if !ok {
+		return ErrNotFound
+	}
+	user = copyUser(user)
+	for i := range user.Inventory {
+		if user.Inventory[i].Rarity == rarity && user.Inventory[i].Quantity > 0 {
+			user.Inventory[i].Quantity--
+			user.Stats.UsedCheatSheets++
+			s.saveWithLedger(user, model.LedgerEntry{
+				Kind:      model.LedgerCheatSheet,
+				Reference: quizID,
+				Items:     []model.ItemMovement{{Rarity: rarity, Quantity: -1}},
+			})
+			return nil
+		}
+	}
+	return ErrInsufficientBalance
+}
+
+func (s *MemoryStore) AdjustCoins(ctx context.Context, userID string, delta int, reason string) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	if user.Coins+delta < 0 {
+		return model.User{}, ErrInsufficientBalance
+	}
+	user = copyUser(user)
+	user.Coins += delta
+	s.saveWithLedger(user, model.LedgerEntry{Kind: model.LedgerAdminGrant, Reference: reason, Coins: delta})
+	return copyUser(s.users[userID]), nil
+}
+
+func (s *MemoryStore) AddAbandonedQuiz(ctx context.Context, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.findUser(func(u model.User) bool { return u.Username == username })
+	if !ok {
+		return ErrNotFound
+	}
+	user.Stats.AbandonedQuizzes++
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *MemoryStore) CreditQuizRewards(ctx context.Context, username string, quizID string, reward model.QuizReward) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.findUser(func(u model.User) bool { return u.Username == username })
+	if !ok {
+		return ErrNotFound
+	}
+	// une récompense de quiz n'est inscrite qu'une fois au journal, comme avec l'index unique de MongoDB
+	for _, entry := range s.ledger {
+		if entry.UserID == user.ID && entry.Kind == model.LedgerQuizReward && entry.Reference == quizID {
+			return ErrNoChange
+		}
+	}
+	user = copyUser(user)
+	user.Coins += reward.Coins
+	user.Experience += reward.Experience
+	user.Stats.PlayedQuizzes++
+	user.Stats.CorrectResponses += reward.CorrectResponses
+	if reward.FullMark {
+		user.Stats.FullMarks++
+	}
+	s.saveWithLedger(user, model.LedgerEntry{Kind: model.LedgerQuizReward, Reference: quizID, Coins: reward.Coins})
+	return nil
+}
+
+func (s *MemoryStore) MarkQuizRewarded(ctx context.Context, quizID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quiz, ok := s.quizzes[quizID]
+	if !ok {
+		return ErrNotFound
+	}
+	quiz.RewardsApplied = true
+	s.quizzes[quizID] = quiz
+	return nil
+}
+
+func (s *MemoryStore) ListUnrewardedQuizzes(ctx context.Context, before time.Time, limit int) ([]model.Quiz, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	quizzes := []model.Quiz{}
+	for _, quiz := range s.quizzes {
+		if len(quizzes) == limit {
+			break
+		}
+		if !quiz.RewardsApplied && quiz.Status == model.QuizFinished && quiz.FinishedAt != nil && quiz.FinishedAt.Before(before) {
+			quizzes = append(quizzes, copyQuiz(quiz))
+		}
+	}
+	return quizzes, nil
+}
+
+func (s *MemoryStore) AdjustInventory(ctx context.Context, userID string, rarity int, delta int, reason string) (model.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	user = copyUser(user)
+	i := 0
+	for i < len(user.Inventory) && user.Inventory[i].Rarity != rarity {
+		i++
+	}
+	if i == len(user.Inventory) {
+		user.Inventory = append(user.Inventory, model.CheatSheet{Rarity: rarity})
+	}
+	if user.Inventory[i].Quantity+delta < 0 {
+		return model.User{}, ErrInsufficientBalance
+	}
+	user.Inventory[i].Quantity += delta
+	s.saveWithLedger(user, model.LedgerEntry{
+		Kind:      model.LedgerAdminGrant,
+		Reference: reason,
+		Items:     []model.ItemMovement{{Rarity: rarity, Quantity: delta}},
+	})
+	return copyUser(s.users[userID]), nil
+}
+
+// ================== Fonctions pour les bannières ==================
+
+func (s *MemoryStore) ListBanners(ctx context.Context) ([]model.Banner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	banners := []model.Banner{}
+	for _, banner := range s.banners {
+		banners = append(banners, copyBanner(banner))
+	}
+	sort.Slice(banners, func(i, j int) bool { return banners[i].ID < banners[j].ID })
+	return banners, nil
+}
+
+func (s *MemoryStore) GetBanner(ctx context.Context, bannerID string) (model.Banner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	banner, ok := s.banners[bannerID]
+	if !ok {
+		return model.Banner{}, ErrNotFound
+	}
+	return copyBanner(banner), nil
+}
+
+func (s *MemoryStore) SaveBanner(ctx context.Context, banner model.Banner) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.banners[banner.ID] = copyBanner(banner)
+	return nil
+}
+
+// ================== Fonctions pour le journal des pièces ==================
+
+func (s *MemoryStore) ListLedgerEntries(ctx context.Context, userID string, skip int, limit int) ([]model.LedgerEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []model.LedgerEntry{}
+	for i := len(s.ledger) - 1; i >= 0 && len(entries) < limit; i-- {
+		if s.ledger[i].UserID != userID {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		entries = append(entries, copyLedgerEntry(s.ledger[i]))
+	}
+	return entries, nil
+}
+
+func (s *MemoryStore) UserLedger(ctx context.Context, userID string) ([]model.LedgerEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []model.LedgerEntry{}
+	for _, entry := range s.ledger {
+		if entry.UserID == userID {
+			entries = append(entries, copyLedgerEntry(entry))
+		}
+	}
+	return entries, nil
+}
+
+// ================== Fonctions pour les catégories ==================
+
+// retourne l'indice de la catégorie vérifiant match, -1 sinon, à appeler avec le verrou
+func (s *MemoryStore) findCategory(match func(model.Category) bool) int {
+	for i, category := range s.categories {
+		if match(category) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryStore) GetUserCategories(ctx context.Context, username string, includeHidden bool) ([]model.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var categories []model.Category
+	for _, category := range s.categories {
+		if (username == "" || category.Username == username) && (includeHidden || !category.Hidden) {
+			categories = append(categories, copyCategory(category))
+		}
+	}
+	return categories, nil
+}
+
+func (s *MemoryStore) CategoryExists(ctx context.Context, categoryName string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.findCategory(func(c model.Category) bool { return c.CategoryName == categoryName }) >= 0, nil
+}
+
+func (s *MemoryStore) GetCategoryByName(ctx context.Context, categoryName string) (model.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findCategory(func(c model.Category) bool { return c.CategoryName == categoryName })
+	if i < 0 {
+		return model.Category{}, ErrNotFound
+	}
+	return copyCategory(s.categories[i]), nil
+}
+
+func (s *MemoryStore) CreateCategory(ctx context.Context, username string, categoryName string, questions []model.Question) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.categories = append(s.categories, model.Category{
+		Username:     username,
+		CategoryName: categoryName,
+		Questions:    copyQuestions(questions),
+	})
+	return nil
+}
+
+func (s *MemoryStore) UpdateCategory(ctx context.Context, username, currentCategoryName, newCategoryName string, questions []model.Question) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findCategory(func(c model.Category) bool {
+		return c.Username == username && c.CategoryName == currentCategoryName
+	})
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.categories[i].Questions = copyQuestions(questions)
+	if newCategoryName != "" {
+		s.categories[i].CategoryName = newCategoryName
+	}
+	return nil
+}
+
+func (s *MemoryStore) CreateQuestion(ctx context.Context, username string, categoryName string, question model.Question) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	question = copyQuestions([]model.Question{question})[0]
+	i := s.findCategory(func(c model.Category) bool { return c.CategoryName == categoryName })
+	if i < 0 {
+		s.categories = append(s.categories, model.Category{
+			Username:     username,
+			CategoryName: categoryName,
+			Questions:    []model.Question{question},
+		})
+		return nil
+	}
+	s.categories[i].Questions = append(s.categories[i].Questions, question)
+	return nil
+}
+
+func (s *MemoryStore) ExistQuestion(ctx context.Context, username string, categoryName string, question model.Question) (bool, model.Question, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findCategory(func(c model.Category) bool {
+		if c.Username != username || c.CategoryName != categoryName {
+			return false
+		}
+		for _, q := range c.Questions {
+			if q.QuestionText == question.QuestionText {
+				return true
+			}
+		}
+		return false
+	})
+	if i < 0 {
+		return false, model.Question{}, nil
+	}
+	return true, question, nil
+}
+
+func (s *MemoryStore) GetQuestionsByCategory(ctx context.Context, categoryName string) []model.Question {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findCategory(func(c model.Category) bool { return c.CategoryName == categoryName })
+	if i < 0 || s.categories[i].Hidden {
+		return []model.Question{}
+	}
+	return copyQuestions(s.categories[i].Questions)
+}
+
+func (s *MemoryStore) SetCategoryHidden(ctx context.Context, categoryName string, hidden bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findCategory(func(c model.Category) bool { return c.CategoryName == categoryName })
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.categories[i].Hidden = hidden
+	return nil
+}
+
+func (s *MemoryStore) DeleteCategory(ctx context.Context, categoryName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findCategory(func(c model.Category) bool { return c.CategoryName == categoryName })
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.categories = append(s.categories[:i], s.categories[i+1:]...)
+	return nil
+}
+
+// ================== Fonctions pour les quiz ==================
+
+func (s *MemoryStore) OnGoingQuiz(ctx context.Context, username string, mode string, category string) (bool, model.Quiz) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, quiz := range s.quizzes {
+		if quiz.Username == username && !quiz.Finish && quiz.Mode == mode && quiz.Category == category {
+			return true, copyQuiz(quiz)
+		}
+	}
+	return false, model.Quiz{}
+}
+
+func (s *MemoryStore) SeenQuestions(ctx context.Context, username string, mode string, category string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := []string{}
+	for _, quiz := range s.quizzes {
+		if quiz.Username != username || quiz.Mode != mode || quiz.Category != category {
+			continue
+		}
+		for _, question := range quiz.Questions {
+			seen = append(seen, question.QuestionText)
+		}
+	}
+	return seen, nil
+}
+
+func (s *MemoryStore) ListOnGoingQuizzes(ctx context.Context, username string) ([]model.Quiz, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	quizzes := []model.Quiz{}
+	for _, quiz := range s.quizzes {
+		if quiz.Username == username && !quiz.Finish {
+			quizzes = append(quizzes, copyQuiz(quiz))
+		}
+	}
+	sort.Slice(quizzes, func(i, j int) bool { return quizzes[i].ID > quizzes[j].ID })
+	return quizzes, nil
+}
+
+func (s *MemoryStore) CreateQuiz(ctx context.Context, quiz model.Quiz) (model.Quiz, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quiz.ID = primitive.NewObjectID().Hex()
+	if quiz.UpdatedAt.IsZero() {
+		quiz.UpdatedAt = time.Now()
+	}
+	s.quizzes[quiz.ID] = copyQuiz(quiz)
+	return quiz, nil
+}
+
+func (s *MemoryStore) GetQuizByID(ctx context.Context, quizID string) (model.Quiz, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	quiz, ok := s.quizzes[quizID]
+	if !ok {
+		return model.Quiz{}, ErrNotFound
+	}
+	return copyQuiz(quiz), nil
+}
+
+func (s *MemoryStore) UpdateQuiz(ctx context.Context, quiz model.Quiz) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.quizzes[quiz.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if stored.Version != quiz.Version {
+		return ErrConflict
+	}
+	quiz.Version++
+	quiz.UpdatedAt = time.Now()
+	// marqué à part par MarkQuizRewarded, comme avec MongoDB
+	quiz.RewardsApplied = stored.RewardsApplied
+	s.quizzes[quiz.ID] = copyQuiz(quiz)
+	return nil
+}
+
+func (s *MemoryStore) ListStaleQuizzes(ctx context.Context, before time.Time, limit int) ([]model.Quiz, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	quizzes := []model.Quiz{}
+	for _, quiz := range s.quizzes {
+		if len(quizzes) == limit {
+			break
+		}
+		if !quiz.Finish && quiz.UpdatedAt.Before(before) {
+			quizzes = append(quizzes, copyQuiz(quiz))
+		}
+	}
+	return quizzes, nil
+}
+
+func (s *MemoryStore) CloseQuiz(ctx context.Context, quizID string, status string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quiz, ok := s.quizzes[quizID]
+	if !ok {
+		return ErrNotFound
+	}
+	if quiz.Finish {
+		return ErrNoChange
+	}
+	quiz.Finish = true
+	quiz.Status = status
+	quiz.FinishedAt = &at
+	quiz.UpdatedAt = at
+	quiz.Version++
+	s.quizzes[quizID] = quiz
+	return nil
+}
+
+func (s *MemoryStore) ListUserQuizzes(ctx context.Context, username string, skip int, limit int) ([]model.Quiz, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	quizzes := []model.Quiz{}
+	for _, quiz := range s.quizzes {
+		if quiz.Username == username && quiz.Finish {
+			quizzes = append(quizzes, quiz)
+		}
+	}
+	sort.Slice(quizzes, func(i, j int) bool { return quizzes[i].ID > quizzes[j].ID })
+	if skip >= len(quizzes) {
+		return []model.Quiz{}, nil
+	}
+	quizzes = quizzes[skip:]
+	if len(quizzes) > limit {
+		quizzes = quizzes[:limit]
+	}
+	for i := range quizzes {
+		quizzes[i] = copyQuiz(quizzes[i])
+	}
+	return quizzes, nil
+}
+
+// ================== Fonctions pour la réserve de questions ==================
+
+func (s *MemoryStore) AddPoolQuestions(ctx context.Context, questions []model.PoolQuestion) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := make(map[string]bool, len(s.pool))
+	for _, question := range s.pool {
+		known[question.Question.QuestionText] = true
+	}
+	added := 0
+	for _, question := range questions {
+		if known[question.Question.QuestionText] {
+			continue
+		}
+		known[question.Question.QuestionText] = true
+		question.ID = primitive.NewObjectID().Hex()
+		question.Question = copyQuestions([]model.Question{question.Question})[0]
+		s.pool = append(s.pool, question)
+		added++
+	}
+	return added, nil
+}
+
+// retourne les questions de la réserve correspondant aux critères, à appeler avec le verrou
+func (s *MemoryStore) matchPool(category, difficulty, questionType string) []model.PoolQuestion {
+	var result []model.PoolQuestion
+	for _, question := range s.pool {
+		if question.Category != category {
+			continue
+		}
+		if difficulty != "" && difficulty != model.DifficultyAny && question.Difficulty != difficulty {
+			continue
+		}
+		if questionType != "" && question.Type != questionType {
+			continue
+		}
+		result = append(result, question)
+	}
+	return result
+}
+
+func (s *MemoryStore) CountPoolQuestions(ctx context.Context, category, difficulty, questionType string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.matchPool(category, difficulty, questionType)), nil
+}
+
+func (s *MemoryStore) SamplePoolQuestions(ctx context.Context, category, difficulty, questionType string, exclude []string, n int) ([]model.PoolQuestion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, text := range exclude {
+		excluded[text] = true
+	}
+	var questions []model.PoolQuestion
+	for _, question := range s.matchPool(category, difficulty, questionType) {
+		if !excluded[question.Question.QuestionText] {
+			questions = append(questions, question)
+		}
+	}
+	rand.Shuffle(len(questions), func(i, j int) { questions[i], questions[j] = questions[j], questions[i] })
+	if len(questions) > n {
+		questions = questions[:n]
+	}
+	for i := range questions {
+		questions[i].Question = copyQuestions([]model.Question{questions[i].Question})[0]
+	}
+	return questions, nil
+}
+
+// ================== Fonctions pour les explications ==================
+
+func (s *MemoryStore) GetExplanation(ctx context.Context, key string) (model.Explanation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	explanation, ok := s.explanations[key]
+	if !ok {
+		return model.Explanation{}, ErrNotFound
+	}
+	return explanation, nil
+}
+
+func (s *MemoryStore) SaveExplanation(ctx context.Context, explanation model.Explanation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.explanations[explanation.Key] = explanation
+	return nil
+}
+
+// ================== Fonctions pour le journal d'audit ==================
+
+func (s *MemoryStore) InsertAuditEntry(ctx context.Context, entry model.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = primitive.NewObjectID().Hex()
+	s.audit = append(s.audit, entry)
+	return nil
+}
+
+func (s *MemoryStore) ListAuditEntries(ctx context.Context, skip int, limit int) ([]model.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []model.AuditEntry
+	for i := len(s.audit) - 1 - skip; i >= 0 && len(entries) < limit; i-- {
+		entries = append(entries, s.audit[i])
+	}
+	return entries, nil
+}
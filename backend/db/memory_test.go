@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"quizmaster/model"
+	"testing"
+)
+
+func TestMemoryStoreUpdateQuiz(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	quiz, err := store.CreateQuiz(ctx, model.Quiz{Username: "alice"})
+	if err != nil {
+		t.Fatalf("CreateQuiz : %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		quiz    model.Quiz
+		wantErr error
+	}{
+		{"version à jour", quiz, nil},
+		{"version périmée", quiz, ErrConflict},
+		{"quiz inconnu", model.Quiz{ID: "inconnu"}, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.UpdateQuiz(ctx, tt.quiz); err != tt.wantErr {
+				t.Errorf("UpdateQuiz = %v, attendu %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	stored, _ := store.GetQuizByID(ctx, quiz.ID)
+	if stored.Version != quiz.Version+1 {
+		t.Errorf("version %d, attendu %d", stored.Version, quiz.Version+1)
+	}
+}
+
+func TestMemoryStoreUsers(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	id, err := store.InsertUser(ctx, model.User{Username: "alice", Inventory: []model.CheatSheet{{Rarity: 3, Quantity: 1}}})
+	if err != nil {
+		t.Fatalf("InsertUser : %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		op      func() error
+		wantErr error
+	}{
+		{"recherche par nom", func() error { _, err := store.GetUserByName(ctx, "alice"); return err }, nil},
+		{"nom inconnu", func() error { _, err := store.GetUserByName(ctx, "bob"); return err }, ErrNotFound},
+		{"changement de nom", func() error { return store.UpdateUserUsername(ctx, id, "alicia") }, nil},
+		{"nom identique", func() error { return store.UpdateUserUsername(ctx, id, "alicia") }, ErrNoChange},
+		{"photo d'un inconnu", func() error { return store.SetUserPicture(ctx, "inconnu", "x.png") }, ErrNotFound},
+		{"suppression", func() error { return store.DeleteUser(ctx, id) }, nil},
+		{"suppression répétée", func() error { return store.DeleteUser(ctx, id) }, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op(); err != tt.wantErr {
+				t.Errorf("erreur %v, attendu %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// les données rendues par le stockage sont des copies
+func TestMemoryStoreCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	id, _ := store.InsertUser(ctx, model.User{Username: "alice", Inventory: []model.CheatSheet{{Rarity: 3, Quantity: 1}}})
+
+	user, _ := store.GetUserByID(ctx, id)
+	user.Inventory[0].Quantity = 99
+	if stored, _ := store.GetUserByID(ctx, id); stored.Inventory[0].Quantity != 1 {
+		t.Errorf("inventaire stocké modifié par l'appelant : %+v", stored.Inventory)
+	}
+}
+
+// crée un utilisateur avec des pièces et une antisèche de rareté 3
+func newTestUser(t *testing.T, store *MemoryStore, username string, coins int) model.User {
+	t.Helper()
+	id, err := store.InsertUser(context.Background(), model.User{
+		Username:  username,
+		Coins:     coins,
+		Inventory: []model.CheatSheet{{Rarity: 3, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("InsertUser : %v", err)
+	}
+	user, err := store.GetUserByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetUserByID : %v", err)
+	}
+	return user
+}
+
+// vérifie que le journal de l'utilisateur correspond à ses soldes
+func assertLedgerConsistent(t *testing.T, store *MemoryStore, userID string) {
+	t.Helper()
+	ctx := context.Background()
+	user, err := store.GetUserByID(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserByID : %v", err)
+	}
+	entries, err := store.UserLedger(ctx, userID)
+	if err != nil {
+		t.Fatalf("UserLedger : %v", err)
+	}
+	if issues := CheckLedger(user, entries); len(issues) > 0 {
+		t.Errorf("journal incohérent : %v", issues)
+	}
+}
+
+func TestMemoryStoreCreditQuizRewards(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	user := newTestUser(t, store, "alice", 100)
+	reward := model.QuizReward{Coins: 50, Experience: 20, CorrectResponses: 5, FullMark: true}
+
+	tests := []struct {
+		name      string
+		username  string
+		quizID    string
+		wantErr   error
+		wantCoins int
+	}{
+		{"premier crédit", "alice", "quiz1", nil, 150},
+		{"quiz déjà récompensé", "alice", "quiz1", ErrNoChange, 150},
+		{"autre quiz", "alice", "quiz2", nil, 200},
+		{"utilisateur inconnu", "bob", "quiz3", ErrNotFound, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.CreditQuizRewards(ctx, tt.username, tt.quizID, reward); err != tt.wantErr {
+				t.Fatalf("CreditQuizRewards = %v, attendu %v", err, tt.wantErr)
+			}
+			got, _ := store.GetUserByID(ctx, user.ID)
+			if got.Coins != tt.wantCoins {
+				t.Errorf("%d pièces, attendu %d", got.Coins, tt.wantCoins)
+			}
+		})
+	}
+
+	got, _ := store.GetUserByID(ctx, user.ID)
+	if got.Stats.PlayedQuizzes != 2 || got.Stats.FullMarks != 2 || got.Experience != 40 {
+		t.Errorf("statistiques %+v et expérience %d après deux quiz", got.Stats, got.Experience)
+	}
+	assertLedgerConsistent(t, store, user.ID)
+}
@@ -3,9 +3,10 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
-	"math/rand"
 	"os"
+	"quizmaster/config"
 	"quizmaster/model"
 	"time"
 
@@ -13,96 +14,185 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-// structure de réponse pour la connexion
-type LoginResponse struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
-	UserID  string `json:"userID"`
-	Token   string `json:"token,omitempty"`
-}
-
 // ================== Fonctions pour les connexions ==================
 
-// se connecte à la base de données MongoDB
-func Connect() *mongo.Client {
+// se connecte à la base de données MongoDB avec un pool de connexions partagé par toute l'application
+func Connect(ctx context.Context) (*mongo.Client, error) {
 	log.Println("Connexion à la base de données")
-	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(os.Getenv("MONGO_URI")))
+	opts := options.Client().
+		ApplyURI(os.Getenv("MONGO_URI")).
+		SetMaxPoolSize(uint64(config.Int("MONGO_MAX_POOL_SIZE", 100))).
+		SetMinPoolSize(uint64(config.Int("MONGO_MIN_POOL_SIZE", 0))).
+		SetMaxConnIdleTime(config.Duration("MONGO_MAX_CONN_IDLE_TIME", 5*time.Minute)).
+		SetConnectTimeout(config.Duration("MONGO_CONNECT_TIMEOUT", 10*time.Second)).
+		SetServerSelectionTimeout(config.Duration("MONGO_SERVER_SELECTION_TIMEOUT", 10*time.Second)).
+		SetTimeout(config.Duration("MONGO_OPERATION_TIMEOUT", 15*time.Second))
+
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		log.Println("Erreur lors du connexion à la base de données")
-		log.Fatal(err)
+		return nil, err
+	}
+
+	// Vérifier que le serveur répond avant d'accepter des requêtes
+	pingCtx, cancel := context.WithTimeout(ctx, config.Duration("MONGO_PING_TIMEOUT", 5*time.Second))
+	defer cancel()
+	if err = client.Ping(pingCtx, readpref.Primary()); err != nil {
+		log.Println("La base de données ne répond pas")
+		client.Disconnect(context.Background())
+		return nil, err
 	}
-	return client
+
+	// Le journal des pièces repose sur des transactions, indisponibles sur un serveur autonome
+	if err = requireTransactions(pingCtx, client); err != nil {
+		log.Println("La base de données ne prend pas en charge les transactions")
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
 }
 
-// se connecte à la base de données et vérifie les identifiants de l'utilisateur
-func Login(client *mongo.Client, username, password string) (LoginResponse, error) {
-	coll := client.Database("DB").Collection("users")
+// vérifie que le serveur est membre d'un replica set ou un routeur mongos, seuls à accepter les transactions
+func requireTransactions(ctx context.Context, client *mongo.Client) error {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return fmt.Errorf("impossible d'interroger le serveur MongoDB : %w", err)
+	}
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return errors.New("MongoDB tourne en serveur autonome : lancez-le en replica set (par exemple mongod --replSet rs0 puis rs.initiate()) pour activer les transactions")
+	}
+	return nil
+}
 
-	// 1. Récupérer l'utilisateur par son username uniquement
-	var user model.User
-	err := coll.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
+// MongoStore implémente Store au-dessus d'une base MongoDB
+type MongoStore struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// NewMongoStore crée un Store qui utilise la base databaseName du client
+func NewMongoStore(client *mongo.Client, databaseName string) *MongoStore {
+	return &MongoStore{client: client, db: client.Database(databaseName)}
+}
+
+// Close ferme la connexion à MongoDB
+func (s *MongoStore) Close() error {
+	return s.client.Disconnect(context.Background())
+}
+
+// EnsureIndexes crée les index nécessaires s'ils n'existent pas encore
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.db.Collection("sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "access_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "refresh_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		// MongoDB supprime lui-même les sessions dont le token de rafraîchissement a expiré
+		{Keys: bson.D{{Key: "refresh_expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return LoginResponse{Status: 401, Message: "Identifiants invalides"}, nil
-		}
-		return LoginResponse{}, err
+		log.Printf("Erreur lors de la création des index : %v\n", err)
+		return err
 	}
 
-	// 2. Comparer le mot de passe fourni avec celui haché en base
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	_, err = s.db.Collection("password_resets").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
 	if err != nil {
-		return LoginResponse{Status: 401, Message: "Identifiants invalides"}, nil
+		log.Printf("Erreur lors de la création des index : %v\n", err)
+		return err
 	}
 
-	log.Printf("Authentification réussie pour l'utilisateur %s\n", user.Username)
-
-	// 3. Génération d'un token (JWT recommandé)
-	token := time.Now().Format(time.RFC3339) + user.Username
-	objID, err := primitive.ObjectIDFromHex(user.ID)
-	_, err = coll.UpdateOne(
-		context.TODO(),
-		bson.M{"_id": objID},
-		bson.M{"$set": bson.M{"token": token}},
-	)
+	_, err = s.db.Collection("question_pool").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// dédoublonnage par texte de question
+		{Keys: bson.D{{Key: "question.question_text", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "category", Value: 1}, {Key: "difficulty", Value: 1}, {Key: "type", Value: 1}}},
+	})
 	if err != nil {
-		return LoginResponse{}, err
+		log.Printf("Erreur lors de la création des index : %v\n", err)
+		return err
 	}
 
-	return LoginResponse{Status: 200, Message: "Authentification réussie", UserID: user.ID, Token: token}, nil
-}
+	_, err = s.db.Collection("Quiz").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "username", Value: 1}, {Key: "finish", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		log.Printf("Erreur lors de la création des index : %v\n", err)
+		return err
+	}
+	// recherche des quiz délaissés
+	_, err = s.db.Collection("Quiz").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "finish", Value: 1}, {Key: "updated_at", Value: 1}},
+	})
+	if err != nil {
+		log.Printf("Erreur lors de la création des index : %v\n", err)
+		return err
+	}
+	// réconciliation des récompenses, seuls les quiz non récompensés sont indexés
+	_, err = s.db.Collection("Quiz").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "finished_at", Value: 1}},
+		Options: options.Index().SetPartialFilterExpression(bson.M{"rewards_applied": false}),
+	})
+	if err != nil {
+		log.Printf("Erreur lors de la création des index : %v\n", err)
+		return err
+	}
 
-// deconnecte l'utilisateur en supprimant le token de la base de données
-func DeleteUserToken(client *mongo.Client, userID string) error {
-	coll := client.Database("DB").Collection("users")
-	objID, err := primitive.ObjectIDFromHex(userID)
+	_, err = s.db.Collection("ledger").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		// un numéro de mouvement ne peut être inscrit qu'une fois par utilisateur
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "seq", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// les récompenses d'un quiz ne peuvent être créditées qu'une fois
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "reference", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"kind": model.LedgerQuizReward}),
+		},
+	})
 	if err != nil {
+		log.Printf("Erreur lors de la création des index : %v\n", err)
 		return err
 	}
-	_, err = coll.UpdateOne(
-		context.TODO(),
-		bson.M{"_id": objID},
-		bson.M{"$set": bson.M{"token": ""}},
-	)
+
+	_, err = s.db.Collection("audit").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "created_at", Value: -1}}})
+	if err != nil {
+		log.Printf("Erreur lors de la création des index : %v\n", err)
+	}
+	return err
+}
+
+// convertit l'erreur "aucun document" du driver en ErrNotFound
+func notFound(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
 	return err
 }
 
 // ================== Fonctions pour User ==================
 
 // InsertUser insère un utilisateur dans la base de données
-func InsertUser(client *mongo.Client, user model.User) (*mongo.InsertOneResult, error) {
+func (s *MongoStore) InsertUser(ctx context.Context, user model.User) (string, error) {
 	log.Println("insertion de l'user dans la bdd")
-	coll := client.Database("DB").Collection("users")
-	return coll.InsertOne(context.TODO(), user)
+	coll := s.db.Collection("users")
+	result, err := coll.InsertOne(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
 }
 
 // UsernameExists vérifie si un nom d'utilisateur existe déjà dans la base de données
-func UsernameExists(client *mongo.Client, username string) (bool, error) {
+func (s *MongoStore) UsernameExists(ctx context.Context, username string) (bool, error) {
 	log.Println("Recherche de l'utilisateur avec le nom d'utilisateur: ", username)
-	coll := client.Database("DB").Collection("users")
-	count, err := coll.CountDocuments(context.TODO(), bson.M{"username": username})
+	coll := s.db.Collection("users")
+	count, err := coll.CountDocuments(ctx, bson.M{"username": username})
 	if err != nil {
 		log.Printf("Erreur lors de la recherche de l'utilisateur avec le nom d'utilisateur: %v\n", err)
 		return false, err
@@ -111,9 +201,8 @@ func UsernameExists(client *mongo.Client, username string) (bool, error) {
 }
 
 // GetAllUsers récupère tous les utilisateurs de la base de données
-func GetAllUsers(client *mongo.Client) ([]model.User, error) {
-	coll := client.Database("DB").Collection("users")
-	ctx := context.TODO()
+func (s *MongoStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	coll := s.db.Collection("users")
 
 	cursor, err := coll.Find(ctx, bson.D{{}})
 	if err != nil {
@@ -130,51 +219,58 @@ func GetAllUsers(client *mongo.Client) ([]model.User, error) {
 }
 
 // GetUserByID recherche un utilisateur par son ID dans la base de données
-func GetUserByID(client *mongo.Client, userID string) (model.User, error) {
+func (s *MongoStore) GetUserByID(ctx context.Context, userID string) (model.User, error) {
 	var user model.User
-	collection := client.Database("DB").Collection("users")
+	collection := s.db.Collection("users")
 
 	objID, err := primitive.ObjectIDFromHex(userID) // je convertis l'ID en ObjectID (type de MongoDB)
 	if err != nil {
 		return user, err // si l'ID n'est pas valide,  retourne une erreur
 	}
 
-	err = collection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&user)
-	return user, err
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&user)
+	return user, notFound(err)
 }
 
-func GetUserByName(client *mongo.Client, username string) (model.User, error) {
+// GetUserByName recherche un utilisateur par son nom dans la base de données
+func (s *MongoStore) GetUserByName(ctx context.Context, username string) (model.User, error) {
 	var user model.User
-	collection := client.Database("DB").Collection("users")
+	collection := s.db.Collection("users")
 
-	err := collection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
+	err := collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
 	if err != nil {
-		return user, err
+		return user, notFound(err)
 	}
 	return user, nil
 }
 
-// GetUserByToken recherche un utilisateur par son token dans la base de données
-func GetUserByToken(client *mongo.Client, token string) (model.User, error) {
-	var user model.User
-	collection := client.Database("DB").Collection("users")
-	log.Println("Recherche de l'utilisateur avec le token: ", token)
-	err := collection.FindOne(context.TODO(), bson.M{"token": token}).Decode(&user)
+// GetTopPlayers retourne les limit joueurs ayant le plus d'expérience
+func (s *MongoStore) GetTopPlayers(ctx context.Context, limit int) ([]model.User, error) {
+	collection := s.db.Collection("users")
+
+	opts := options.Find().SetSort(bson.D{{Key: "experience", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
 	if err != nil {
-		return user, err
+		return nil, err
 	}
-	return user, nil
+	defer cursor.Close(ctx)
+
+	var users []model.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
 }
 
 // SetUserPicture met à jour l'image de profil de l'utilisateur
-func SetUserPicture(client *mongo.Client, userId string, picture string) error {
-	coll := client.Database("DB").Collection("users")
+func (s *MongoStore) SetUserPicture(ctx context.Context, userId string, picture string) error {
+	coll := s.db.Collection("users")
 	id, err := primitive.ObjectIDFromHex(userId)
 	if err != nil {
 		return err
 	}
 	_, err = coll.UpdateOne(
-		context.TODO(),
+		ctx,
 		bson.M{"_id": id},
 		bson.M{"$set": bson.M{"picture": picture}},
 	)
@@ -182,22 +278,22 @@ func SetUserPicture(client *mongo.Client, userId string, picture string) error {
 }
 
 // DeleteUser supprime un utilisateur de la base de données
-func DeleteUser(client *mongo.Client, userID string) error {
-	coll := client.Database("DB").Collection("users")
+func (s *MongoStore) DeleteUser(ctx context.Context, userID string) error {
+	coll := s.db.Collection("users")
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		log.Printf("Erreur lors de la conversion de l'ID utilisateur en ObjectID : %v\n", err)
 		return err
 	}
 
-	result, err := coll.DeleteOne(context.TODO(), bson.M{"_id": objID})
+	result, err := coll.DeleteOne(ctx, bson.M{"_id": objID})
 	if err != nil {
 		log.Printf("Erreur lors de la suppression de l'utilisateur : %v\n", err)
 		return err
 	}
 
 	if result.DeletedCount == 0 {
-		return errors.New("Aucun utilisateur supprimé")
+		return ErrNotFound
 	}
 
 	log.Printf("Utilisateur supprimé avec succès : %v\n", result.DeletedCount)
@@ -205,8 +301,8 @@ func DeleteUser(client *mongo.Client, userID string) error {
 }
 
 // UpdateUserUsername met à jour l'username de l'utilisateur
-func UpdateUserUsername(client *mongo.Client, userID string, newUsername string) error {
-	coll := client.Database("DB").Collection("users")
+func (s *MongoStore) UpdateUserUsername(ctx context.Context, userID string, newUsername string) error {
+	coll := s.db.Collection("users")
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		log.Printf("Erreur lors de la conversion de l'ID utilisateur en ObjectID : %v\n", err)
@@ -214,7 +310,7 @@ func UpdateUserUsername(client *mongo.Client, userID string, newUsername string)
 	}
 
 	result, err := coll.UpdateOne(
-		context.TODO(),
+		ctx,
 		bson.M{"_id": objID},
 		bson.M{"$set": bson.M{"username": newUsername}},
 	)
@@ -224,7 +320,7 @@ func UpdateUserUsername(client *mongo.Client, userID string, newUsername string)
 	}
 
 	if result.ModifiedCount == 0 {
-		return errors.New("Aucune mise à jour effectuée")
+		return ErrNoChange
 	}
 
 	log.Printf("Username de l'utilisateur mis à jour avec succès. Documents affectés: %v\n", result.ModifiedCount)
@@ -232,8 +328,8 @@ func UpdateUserUsername(client *mongo.Client, userID string, newUsername string)
 }
 
 // UpdateUserPassword met à jour le mot de passe de l'utilisateur
-func UpdateUserPassword(client *mongo.Client, userID string, newPassword string) error {
-	coll := client.Database("DB").Collection("users")
+func (s *MongoStore) UpdateUserPassword(ctx context.Context, userID string, newPassword string) error {
+	coll := s.db.Collection("users")
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		log.Printf("Erreur lors de la conversion de l'ID utilisateur en ObjectID : %v\n", err)
@@ -241,7 +337,7 @@ func UpdateUserPassword(client *mongo.Client, userID string, newPassword string)
 	}
 
 	result, err := coll.UpdateOne(
-		context.TODO(),
+		ctx,
 		bson.M{"_id": objID},
 		bson.M{"$set": bson.M{"password": newPassword}},
 	)
@@ -251,125 +347,750 @@ func UpdateUserPassword(client *mongo.Client, userID string, newPassword string)
 	}
 
 	if result.ModifiedCount == 0 {
-		return errors.New("Aucune mise à jour effectuée")
+		return ErrNoChange
 	}
 
 	log.Printf("Mot de passe de l'utilisateur mis à jour avec succès. Documents affectés: %v\n", result.ModifiedCount)
 	return nil
 }
 
-func GetUserByUsername(client *mongo.Client, username string) (model.User, error) {
-	var user model.User
-	collection := client.Database("DB").Collection("users")
-
-	err := collection.FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
-	if err != nil {
-		log.Printf("Erreur lors de la recherche de l'utilisateur: %v\n", err)
-		return user, err
-	}
-	return user, nil
-}
+// ================== Fonctions pour les quiz ==================
 
-// Quiz non terminé par un utilisateur
-func OnGoingQuiz(client *mongo.Client, userName string) (bool, model.Quiz) {
-	filter := bson.M{"username": userName, "finish": false}
+// Quiz non terminé par un utilisateur pour un mode et une catégorie
+func (s *MongoStore) OnGoingQuiz(ctx context.Context, userName string, mode string, category string) (bool, model.Quiz) {
+	filter := bson.M{"username": userName, "finish": false, "mode": mode, "category": category}
 
 	var quiz model.Quiz
-	coll := client.Database("DB").Collection("Quiz")
-	err := coll.FindOne(context.TODO(), filter).Decode(&quiz)
+	coll := s.db.Collection("Quiz")
+	err := coll.FindOne(ctx, filter).Decode(&quiz)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return false, model.Quiz{}
+		if err != mongo.ErrNoDocuments {
+			log.Printf("Erreur lors de la recherche du quiz en cours : %v\n", err)
 		}
 		return false, model.Quiz{}
 	}
 	return true, quiz
 }
 
+// SeenQuestions retourne le texte des questions des quiz de l'utilisateur pour ce mode et cette catégorie
+func (s *MongoStore) SeenQuestions(ctx context.Context, userName string, mode string, category string) ([]string, error) {
+	filter := bson.M{"username": userName, "mode": mode, "category": category}
+	values, err := s.db.Collection("Quiz").Distinct(ctx, "questions.question_text", filter)
+	if err != nil {
+		return nil, err
+	}
+	seen := make([]string, 0, len(values))
+	for _, value := range values {
+		if text, ok := value.(string); ok {
+			seen = append(seen, text)
+		}
+	}
+	return seen, nil
+}
+
+// ListOnGoingQuizzes retourne les quiz en cours d'un utilisateur, du plus récent au plus ancien
+func (s *MongoStore) ListOnGoingQuizzes(ctx context.Context, userName string) ([]model.Quiz, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	cursor, err := s.db.Collection("Quiz").Find(ctx, bson.M{"username": userName, "finish": false}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	quizzes := []model.Quiz{}
+	if err = cursor.All(ctx, &quizzes); err != nil {
+		return nil, err
+	}
+	return quizzes, nil
+}
+
 // Create a Quiz
-func CreateQuiz(client *mongo.Client, quiz model.Quiz) (model.Quiz, error) {
-	coll := client.Database("DB").Collection("Quiz")
+func (s *MongoStore) CreateQuiz(ctx context.Context, quiz model.Quiz) (model.Quiz, error) {
+	coll := s.db.Collection("Quiz")
 	log.Println("Création d'un quiz par l'API externe")
-	result, err := coll.InsertOne(context.TODO(), quiz)
+	if quiz.UpdatedAt.IsZero() {
+		quiz.UpdatedAt = time.Now()
+	}
+	result, err := coll.InsertOne(ctx, quiz)
+	if err != nil {
+		return quiz, err
+	}
 	quiz.ID = result.InsertedID.(primitive.ObjectID).Hex()
-	return quiz, err
+	return quiz, nil
 }
 
-func GetQuizByID(client *mongo.Client, quizID string) (model.Quiz, error) {
+// GetQuizByID recherche un quiz par son ID
+func (s *MongoStore) GetQuizByID(ctx context.Context, quizID string) (model.Quiz, error) {
 	var quiz model.Quiz
-	collection := client.Database("DB").Collection("Quiz")
+	collection := s.db.Collection("Quiz")
 
 	objID, err := primitive.ObjectIDFromHex(quizID)
 	if err != nil {
 		return quiz, err
 	}
 
-	err = collection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&quiz)
-	return quiz, err
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&quiz)
+	return quiz, notFound(err)
 }
 
-func UpdateQuiz(client *mongo.Client, quiz model.Quiz) (*mongo.UpdateResult, error) {
-	coll := client.Database("DB").Collection("Quiz")
+// UpdateQuiz enregistre l'avancement du quiz
+func (s *MongoStore) UpdateQuiz(ctx context.Context, quiz model.Quiz) error {
+	coll := s.db.Collection("Quiz")
 	objID, err := primitive.ObjectIDFromHex(quiz.ID)
 	if err != nil {
 		log.Printf("Erreur lors de la conversion de l'ID du quiz en ObjectID : %v\n", err)
-		return nil, err
+		return err
 	}
 
 	updateData := bson.M{
-		"username":        quiz.Username,
-		"questions":       quiz.Questions,
-		"mark":            quiz.Mark,
-		"finish":          quiz.Finish,
-		"number_question": quiz.Number_question,
+		"username":          quiz.Username,
+		"questions":         quiz.Questions,
+		"mark":              quiz.Mark,
+		"finish":            quiz.Finish,
+		"number_question":   quiz.Number_question,
+		"answers":           quiz.Answers,
+		"points":            quiz.Points,
+		"finished_at":       quiz.FinishedAt,
+		"cheat_sheets_used": quiz.CheatSheetsUsed,
+		"status":            quiz.Status,
+		"updated_at":        time.Now(),
+	}
+
+	// la mise à jour n'a lieu que si personne n'a modifié le quiz depuis sa lecture
+	filter := bson.M{"_id": objID, "version": quiz.Version}
+	if quiz.Version == 0 {
+		// les quiz créés avant le suivi des versions n'ont pas le champ
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
 	}
 
 	log.Printf("Mise à jour du quiz avec l'ID : %s\n", quiz.ID)
-	return coll.UpdateOne(
-		context.TODO(),
-		bson.M{"_id": objID},
+	result, err := coll.UpdateOne(
+		ctx,
+		filter,
 		bson.M{
 			"$set": updateData,
+			"$inc": bson.M{"version": 1},
 		},
 	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		// distingue un quiz supprimé d'un quiz modifié entre-temps
+		count, err := coll.CountDocuments(ctx, bson.M{"_id": objID})
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+// ListUserQuizzes retourne les quiz terminés d'un utilisateur, du plus récent au plus ancien.
+// Le tri se fait sur l'ID, qui suit l'ordre de création, pour inclure les quiz sans date de création.
+func (s *MongoStore) ListUserQuizzes(ctx context.Context, username string, skip int, limit int) ([]model.Quiz, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetSkip(int64(skip)).SetLimit(int64(limit))
+	cursor, err := s.db.Collection("Quiz").Find(ctx, bson.M{"username": username, "finish": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	quizzes := []model.Quiz{}
+	if err = cursor.All(ctx, &quizzes); err != nil {
+		return nil, err
+	}
+	return quizzes, nil
 }
 
-func UpdateUser(client *mongo.Client, user model.User) (*mongo.UpdateResult, error) {
-	coll := client.Database("DB").Collection("users")
+// ListStaleQuizzes retourne jusqu'à limit quiz en cours sans activité depuis before.
+// Les quiz créés avant l'enregistrement de l'activité sont datés par leur ObjectID.
+func (s *MongoStore) ListStaleQuizzes(ctx context.Context, before time.Time, limit int) ([]model.Quiz, error) {
+	filter := bson.M{
+		"finish": false,
+		"$or": bson.A{
+			bson.M{"updated_at": bson.M{"$lt": before}},
+			bson.M{"updated_at": bson.M{"$exists": false}, "_id": bson.M{"$lt": primitive.NewObjectIDFromTimestamp(before)}},
+		},
+	}
+	cursor, err := s.db.Collection("Quiz").Find(ctx, filter, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	quizzes := []model.Quiz{}
+	if err = cursor.All(ctx, &quizzes); err != nil {
+		return nil, err
+	}
+	return quizzes, nil
+}
+
+// CloseQuiz termine un quiz encore en cours avec l'état status
+func (s *MongoStore) CloseQuiz(ctx context.Context, quizID string, status string, at time.Time) error {
+	objID, err := primitive.ObjectIDFromHex(quizID)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.Collection("Quiz").UpdateOne(
+		ctx,
+		bson.M{"_id": objID, "finish": false},
+		bson.M{
+			"$set": bson.M{"finish": true, "status": status, "finished_at": at, "updated_at": at},
+			"$inc": bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNoChange
+	}
+	return nil
+}
+
+// UpdateUser enregistre l'expérience de l'utilisateur. Les pièces et l'inventaire ne changent que
+// par les mouvements inscrits au journal (PullCheatSheets, AdjustCoins...).
+func (s *MongoStore) UpdateUser(ctx context.Context, user model.User) error {
+	coll := s.db.Collection("users")
 	objID, err := primitive.ObjectIDFromHex(user.ID)
 	if err != nil {
 		log.Printf("Erreur lors de la conversion de l'ID utilisateur en ObjectID : %v\n", err)
-		return nil, err
+		return err
 	}
 
 	updateData := bson.M{
-		"experience":              user.Experience,
-		"coins":                   user.Coins,
-		"inventory":               user.Inventory,
-		"stats.quizzes_played":    user.Stats.PlayedQuizzes,
-		"stats.correct_responses": user.Stats.CorrectResponses,
-		"stats.full_marks":        user.Stats.FullMarks,
-		"stats.used_cheat_sheets": user.Stats.UsedCheatSheets,
-	}
-
-	log.Printf("Mise à jour de l'inventaire de l'utilisateur avec l'ID : %s\n", user.ID)
-	return coll.UpdateOne(
-		context.TODO(),
+		"experience": user.Experience,
+	}
+
+	log.Printf("Mise à jour de l'expérience de l'utilisateur avec l'ID : %s\n", user.ID)
+	_, err = coll.UpdateOne(
+		ctx,
 		bson.M{"_id": objID},
 		bson.M{
 			"$set": updateData,
 		},
 	)
+	return err
+}
+
+// met à jour un champ de l'utilisateur userID
+func (s *MongoStore) setUserField(ctx context.Context, userID string, field string, value interface{}) error {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.Collection("users").UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{field: value}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetUserRole change le rôle de l'utilisateur
+func (s *MongoStore) SetUserRole(ctx context.Context, userID string, role string) error {
+	return s.setUserField(ctx, userID, "role", role)
+}
+
+// SetUserBanned bannit ou débannit l'utilisateur
+func (s *MongoStore) SetUserBanned(ctx context.Context, userID string, banned bool) error {
+	return s.setUserField(ctx, userID, "banned", banned)
+}
+
+// SetUserEmail change l'adresse e-mail de l'utilisateur
+func (s *MongoStore) SetUserEmail(ctx context.Context, userID string, email string) error {
+	return s.setUserField(ctx, userID, "email", email)
+}
 
+// SetUserOpenTDBToken enregistre le token de session Open Trivia DB de l'utilisateur
+func (s *MongoStore) SetUserOpenTDBToken(ctx context.Context, userID string, token string) error {
+	return s.setUserField(ctx, userID, "opentdb_token", token)
 }
 
-func CreateQuestion(client *mongo.Client, userName string, categoryName string, question model.Question) error {
-	coll := client.Database("DB").Collection("categories")
+// ================== Fonctions pour les sessions ==================
+
+// CreateSession enregistre une nouvelle session
+func (s *MongoStore) CreateSession(ctx context.Context, session model.Session) (model.Session, error) {
+	coll := s.db.Collection("sessions")
+	result, err := coll.InsertOne(ctx, session)
+	if err != nil {
+		return session, err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return session, nil
+}
+
+// GetSessionByAccessHash recherche une session par l'empreinte de son token d'accès
+func (s *MongoStore) GetSessionByAccessHash(ctx context.Context, accessHash string) (model.Session, error) {
+	var session model.Session
+	err := s.db.Collection("sessions").FindOne(ctx, bson.M{"access_hash": accessHash}).Decode(&session)
+	return session, notFound(err)
+}
+
+// GetSessionByRefreshHash recherche une session par l'empreinte de son token de rafraîchissement
+func (s *MongoStore) GetSessionByRefreshHash(ctx context.Context, refreshHash string) (model.Session, error) {
+	var session model.Session
+	err := s.db.Collection("sessions").FindOne(ctx, bson.M{"refresh_hash": refreshHash}).Decode(&session)
+	return session, notFound(err)
+}
+
+// RotateSession remplace les tokens de la session, uniquement si oldRefreshHash n'a pas déjà été utilisé
+func (s *MongoStore) RotateSession(ctx context.Context, oldRefreshHash string, next model.Session) error {
+	coll := s.db.Collection("sessions")
+	objID, err := primitive.ObjectIDFromHex(next.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := coll.UpdateOne(
+		ctx,
+		bson.M{"_id": objID, "refresh_hash": oldRefreshHash},
+		bson.M{"$set": bson.M{
+			"access_hash":        next.AccessHash,
+			"refresh_hash":       next.RefreshHash,
+			"expires_at":         next.ExpiresAt,
+			"refresh_expires_at": next.RefreshExpiresAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSession révoque une session
+func (s *MongoStore) DeleteSession(ctx context.Context, sessionID string) error {
+	objID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Collection("sessions").DeleteOne(ctx, bson.M{"_id": objID})
+	return err
+}
+
+// DeleteUserSessions révoque toutes les sessions de l'utilisateur et retourne leur nombre
+func (s *MongoStore) DeleteUserSessions(ctx context.Context, userID string) (int, error) {
+	result, err := s.db.Collection("sessions").DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// DeleteOtherSessions révoque toutes les sessions de l'utilisateur sauf keepSessionID
+func (s *MongoStore) DeleteOtherSessions(ctx context.Context, userID string, keepSessionID string) (int, error) {
+	keepID, err := primitive.ObjectIDFromHex(keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.Collection("sessions").DeleteMany(ctx, bson.M{"user_id": userID, "_id": bson.M{"$ne": keepID}})
+	if err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
+// ================== Fonctions pour la réinitialisation de mot de passe ==================
+
+// CreatePasswordReset enregistre une demande de réinitialisation
+func (s *MongoStore) CreatePasswordReset(ctx context.Context, reset model.PasswordReset) (model.PasswordReset, error) {
+	result, err := s.db.Collection("password_resets").InsertOne(ctx, reset)
+	if err != nil {
+		return reset, err
+	}
+	reset.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return reset, nil
+}
+
+// GetPasswordResetByHash recherche une demande par l'empreinte de son token
+func (s *MongoStore) GetPasswordResetByHash(ctx context.Context, tokenHash string) (model.PasswordReset, error) {
+	var reset model.PasswordReset
+	err := s.db.Collection("password_resets").FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&reset)
+	return reset, notFound(err)
+}
+
+// UsePasswordReset marque la demande comme utilisée, ErrNotFound si elle l'était déjà
+func (s *MongoStore) UsePasswordReset(ctx context.Context, resetID string) error {
+	objID, err := primitive.ObjectIDFromHex(resetID)
+	if err != nil {
+		return err
+	}
+	result, err := s.db.Collection("password_resets").UpdateOne(
+		ctx,
+		bson.M{"_id": objID, "used_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"used_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUserPasswordResets supprime les demandes de réinitialisation de l'utilisateur
+func (s *MongoStore) DeleteUserPasswordResets(ctx context.Context, userID string) error {
+	_, err := s.db.Collection("password_resets").DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
+// ================== Fonctions pour l'inventaire ==================
+
+// withLedger applique un mouvement et l'inscrit au journal dans une même transaction :
+// move modifie l'utilisateur (en incrémentant ledger_seq) et retourne son état après la
+// modification, le mouvement est enregistré avec ces soldes. Si l'écriture au journal échoue,
+// la modification est annulée.
+func (s *MongoStore) withLedger(ctx context.Context, entry model.LedgerEntry, move func(ctx mongo.SessionContext) (model.User, error)) (model.User, error) {
+	var user model.User
+	session, err := s.client.StartSession()
+	if err != nil {
+		return user, err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var err error
+		user, err = move(sc)
+		if err != nil {
+			return nil, err
+		}
+		_, err = s.db.Collection("ledger").InsertOne(sc, newLedgerEntry(user, entry))
+		return nil, err
+	})
+	if err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// PullCheatSheets débite le prix d'un tirage et ajoute les antisèches obtenues, seulement si le solde
+// suffit. Les entrées d'inventaire des raretés que l'utilisateur n'a pas encore ne sont créées
+// qu'une fois le débit accepté, dans la même transaction.
+func (s *MongoStore) PullCheatSheets(ctx context.Context, username string, bannerID string, price int, pulled map[int]int) (model.User, error) {
+	return s.withLedger(ctx, pullLedgerEntry(bannerID, price, pulled), func(ctx mongo.SessionContext) (model.User, error) {
+		return s.pullCheatSheets(ctx, username, price, pulled)
+	})
+}
+
+func (s *MongoStore) pullCheatSheets(ctx context.Context, username string, price int, pulled map[int]int) (model.User, error) {
+	coll := s.db.Collection("users")
+	user, err := s.GetUserByName(ctx, username)
+	if err != nil {
+		return user, err
+	}
+	owned := make(map[int]bool, len(user.Inventory))
+	for _, item := range user.Inventory {
+		owned[item.Rarity] = true
+	}
+
+	// débit et antisèches des raretés déjà présentes, en une mise à jour conditionnée au solde
+	inc := bson.M{"coins": -price, "ledger_seq": 1}
+	var filters []interface{}
+	var missing []model.CheatSheet
+	for _, rarity := range sortedKeys(pulled) {
+		if !owned[rarity] {
+			missing = append(missing, model.CheatSheet{Rarity: rarity, Quantity: pulled[rarity]})
+			continue
+		}
+		id := fmt.Sprintf("r%d", rarity)
+		inc["inventory.$["+id+"].quantity"] = pulled[rarity]
+		filters = append(filters, bson.M{id + ".rarity": rarity})
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if len(filters) > 0 {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: filters})
+	}
+
+	err = coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"username": username, "coins": bson.M{"$gte": price}},
+		bson.M{"$inc": inc},
+		opts,
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, ErrInsufficientBalance
+	}
+	if err != nil || len(missing) == 0 {
+		return user, err
+	}
+
+	// nouvelles raretés, ajoutées avec leur quantité une fois le débit accepté
+	err = coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"username": username},
+		bson.M{"$push": bson.M{"inventory": bson.M{"$each": missing}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	return user, err
+}
+
+// ConsumeCheatSheet retire une antisèche de la rareté donnée et met à jour les statistiques
+func (s *MongoStore) ConsumeCheatSheet(ctx context.Context, username string, rarity int, quizID string) error {
+	entry := model.LedgerEntry{
+		Kind:      model.LedgerCheatSheet,
+		Reference: quizID,
+		Items:     []model.ItemMovement{{Rarity: rarity, Quantity: -1}},
+	}
+	_, err := s.withLedger(ctx, entry, func(ctx mongo.SessionContext) (model.User, error) {
+		return s.consumeCheatSheet(ctx, username, rarity)
+	})
+	return err
+}
+
+func (s *MongoStore) consumeCheatSheet(ctx context.Context, username string, rarity int) (model.User, error) {
+	var user model.User
+	filter := bson.M{
+		"username":  username,
+		"inventory": bson.M{"$elemMatch": bson.M{"rarity": rarity, "quantity": bson.M{"$gte": 1}}},
+	}
+	update := bson.M{
+		"$inc": bson.M{
+			"inventory.$.quantity":    -1,
+			"stats.used_cheat_sheets": 1,
+			"ledger_seq":              1,
+		},
+	}
+	err := s.db.Collection("users").FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		if _, err = s.GetUserByName(ctx, username); err != nil {
+			return user, err
+		}
+		return user, ErrInsufficientBalance
+	}
+	return user, err
+}
+
+// AdjustCoins ajoute delta (éventuellement négatif) aux pièces de l'utilisateur et retourne l'utilisateur mis à jour.
+// Le solde ne peut pas devenir négatif.
+func (s *MongoStore) AdjustCoins(ctx context.Context, userID string, delta int, reason string) (model.User, error) {
+	entry := model.LedgerEntry{Kind: model.LedgerAdminGrant, Reference: reason, Coins: delta}
+	return s.withLedger(ctx, entry, func(ctx mongo.SessionContext) (model.User, error) {
+		return s.adjustCoins(ctx, userID, delta)
+	})
+}
+
+func (s *MongoStore) adjustCoins(ctx context.Context, userID string, delta int) (model.User, error) {
+	var user model.User
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return user, err
+	}
+
+	filter := bson.M{"_id": objID}
+	if delta < 0 {
+		filter["coins"] = bson.M{"$gte": -delta}
+	}
+	err = s.db.Collection("users").FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$inc": bson.M{"coins": delta, "ledger_seq": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		if _, err = s.GetUserByID(ctx, userID); err != nil {
+			return user, err
+		}
+		return user, ErrInsufficientBalance
+	}
+	return user, err
+}
+
+// AddAbandonedQuiz compte un quiz abandonné ou expiré dans les statistiques de l'utilisateur
+func (s *MongoStore) AddAbandonedQuiz(ctx context.Context, username string) error {
+	_, err := s.db.Collection("users").UpdateOne(
+		ctx,
+		bson.M{"username": username},
+		bson.M{"$inc": bson.M{"stats.abandoned_quizzes": 1}},
+	)
+	return err
+}
+
+// CreditQuizRewards incrémente les pièces, l'expérience et les statistiques de l'utilisateur et inscrit
+// la récompense au journal. L'index unique sur (user_id, reference) des récompenses de quiz empêche
+// de créditer deux fois le même quiz, même en concurrence.
+func (s *MongoStore) CreditQuizRewards(ctx context.Context, username string, quizID string, reward model.QuizReward) error {
+	entry := model.LedgerEntry{Kind: model.LedgerQuizReward, Reference: quizID, Coins: reward.Coins}
+	_, err := s.withLedger(ctx, entry, func(ctx mongo.SessionContext) (model.User, error) {
+		return s.creditQuizRewards(ctx, username, quizID, reward)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrNoChange
+	}
+	return err
+}
+
+func (s *MongoStore) creditQuizRewards(ctx context.Context, username string, quizID string, reward model.QuizReward) (model.User, error) {
+	inc := bson.M{
+		"coins":                   reward.Coins,
+		"experience":              reward.Experience,
+		"stats.quizzes_played":    1,
+		"stats.correct_responses": reward.CorrectResponses,
+		"ledger_seq":              1,
+	}
+	if reward.FullMark {
+		inc["stats.full_marks"] = 1
+	}
+
+	user, err := s.GetUserByName(ctx, username)
+	if err != nil {
+		return user, err
+	}
+	// récompense déjà au journal : rien à créditer
+	count, err := s.db.Collection("ledger").CountDocuments(ctx, bson.M{
+		"user_id":   user.ID,
+		"kind":      model.LedgerQuizReward,
+		"reference": quizID,
+	})
+	if err != nil {
+		return user, err
+	}
+	if count > 0 {
+		return user, ErrNoChange
+	}
+
+	err = s.db.Collection("users").FindOneAndUpdate(
+		ctx,
+		bson.M{"username": username},
+		bson.M{"$inc": inc},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+// MarkQuizRewarded note sur le quiz que ses récompenses ont été créditées
+func (s *MongoStore) MarkQuizRewarded(ctx context.Context, quizID string) error {
+	objID, err := primitive.ObjectIDFromHex(quizID)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Collection("Quiz").UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": bson.M{"rewards_applied": true}})
+	return err
+}
+
+// ListUnrewardedQuizzes liste les quiz terminés normalement avant before sans récompense créditée
+func (s *MongoStore) ListUnrewardedQuizzes(ctx context.Context, before time.Time, limit int) ([]model.Quiz, error) {
+	filter := bson.M{
+		"rewards_applied": false,
+		"status":          model.QuizFinished,
+		"finished_at":     bson.M{"$lt": before},
+	}
+	cursor, err := s.db.Collection("Quiz").Find(ctx, filter, options.Find().SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	quizzes := []model.Quiz{}
+	if err = cursor.All(ctx, &quizzes); err != nil {
+		return nil, err
+	}
+	return quizzes, nil
+}
+
+// AdjustInventory ajoute delta antisèches de la rareté donnée, l'entrée d'inventaire est créée si besoin.
+// La quantité ne peut pas devenir négative.
+func (s *MongoStore) AdjustInventory(ctx context.Context, userID string, rarity int, delta int, reason string) (model.User, error) {
+	entry := model.LedgerEntry{
+		Kind:      model.LedgerAdminGrant,
+		Reference: reason,
+		Items:     []model.ItemMovement{{Rarity: rarity, Quantity: delta}},
+	}
+	return s.withLedger(ctx, entry, func(ctx mongo.SessionContext) (model.User, error) {
+		return s.adjustInventory(ctx, userID, rarity, delta)
+	})
+}
+
+func (s *MongoStore) adjustInventory(ctx context.Context, userID string, rarity int, delta int) (model.User, error) {
+	var user model.User
+	coll := s.db.Collection("users")
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return user, err
+	}
+
+	// Créer l'entrée de cette rareté si elle n'existe pas encore
+	_, err = coll.UpdateOne(
+		ctx,
+		bson.M{"_id": objID, "inventory.rarity": bson.M{"$ne": rarity}},
+		bson.M{"$push": bson.M{"inventory": model.CheatSheet{Rarity: rarity, Quantity: 0}}},
+	)
+	if err != nil {
+		return user, err
+	}
+
+	match := bson.M{"rarity": rarity}
+	if delta < 0 {
+		match["quantity"] = bson.M{"$gte": -delta}
+	}
+	err = coll.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objID, "inventory": bson.M{"$elemMatch": match}},
+		bson.M{"$inc": bson.M{"inventory.$.quantity": delta, "ledger_seq": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		if _, err = s.GetUserByID(ctx, userID); err != nil {
+			return user, err
+		}
+		return user, ErrInsufficientBalance
+	}
+	return user, err
+}
+
+// ListLedgerEntries retourne les mouvements de l'utilisateur, du plus récent au plus ancien
+func (s *MongoStore) ListLedgerEntries(ctx context.Context, userID string, skip int, limit int) ([]model.LedgerEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: -1}}).SetSkip(int64(skip)).SetLimit(int64(limit))
+	cursor, err := s.db.Collection("ledger").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []model.LedgerEntry{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// UserLedger retourne tous les mouvements de l'utilisateur dans l'ordre de leur numéro
+func (s *MongoStore) UserLedger(ctx context.Context, userID string) ([]model.LedgerEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	cursor, err := s.db.Collection("ledger").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []model.LedgerEntry{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ================== Fonctions pour les catégories ==================
+
+func (s *MongoStore) CreateQuestion(ctx context.Context, userName string, categoryName string, question model.Question) error {
+	coll := s.db.Collection("categories")
 	filter := bson.M{"categoryname": categoryName}
 
 	// Vérifier si la catégorie existe déjà
 	var existingCategory model.Category
-	err := coll.FindOne(context.TODO(), filter).Decode(&existingCategory)
+	err := coll.FindOne(ctx, filter).Decode(&existingCategory)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			// La catégorie n'existe pas, créer un nouveau document
@@ -378,7 +1099,7 @@ func CreateQuestion(client *mongo.Client, userName string, categoryName string,
 				CategoryName: categoryName,
 				Questions:    []model.Question{question},
 			}
-			_, err = coll.InsertOne(context.TODO(), newCategory)
+			_, err = coll.InsertOne(ctx, newCategory)
 			if err != nil {
 				log.Printf("Erreur lors de la création de la catégorie : %v", err)
 				return err
@@ -393,14 +1114,14 @@ func CreateQuestion(client *mongo.Client, userName string, categoryName string,
 	update := bson.M{
 		"$push": bson.M{"questions": question},
 	}
-	_, err = coll.UpdateOne(context.TODO(), filter, update)
+	_, err = coll.UpdateOne(ctx, filter, update)
 	if err != nil {
 		log.Printf("Erreur MongoDB: %v", err)
 	}
 	return err
 }
 
-func ExistQuestion(client *mongo.Client, userName string, categoryName string, question model.Question) (bool, model.Question, error) {
+func (s *MongoStore) ExistQuestion(ctx context.Context, userName string, categoryName string, question model.Question) (bool, model.Question, error) {
 	filter := bson.M{
 		"username":                userName,
 		"categoryname":            categoryName,
@@ -411,9 +1132,9 @@ func ExistQuestion(client *mongo.Client, userName string, categoryName string, q
 
 	var result model.Category
 
-	coll := client.Database("DB").Collection("categories")
+	coll := s.db.Collection("categories")
 	log.Println("collection existe : ", coll)
-	err := coll.FindOne(context.TODO(), filter).Decode(&result)
+	err := coll.FindOne(ctx, filter).Decode(&result)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -427,108 +1148,41 @@ func ExistQuestion(client *mongo.Client, userName string, categoryName string, q
 	log.Printf("✅ Question trouvée dans la catégorie %s", categoryName)
 	return true, question, nil
 }
-func GetQuestionsByCategory(client *mongo.Client, categoryName string) []model.Question {
-	filter := bson.M{"categoryname": categoryName}
+
+func (s *MongoStore) GetQuestionsByCategory(ctx context.Context, categoryName string) []model.Question {
+	filter := bson.M{"categoryname": categoryName, "hidden": bson.M{"$ne": true}}
 	var category model.Category
-	coll := client.Database("DB").Collection("categories")
-	err := coll.FindOne(context.TODO(), filter).Decode(&category)
+	coll := s.db.Collection("categories")
+	err := coll.FindOne(ctx, filter).Decode(&category)
 	if err != nil {
 		return []model.Question{}
 	}
 	return category.Questions
 }
 
-func GetCheatSheet(client *mongo.Client, userName string, number_pull int) ([]int, error) {
-	coll := client.Database("DB").Collection("users")
-
-	var user model.User
-	err := coll.FindOne(context.TODO(), bson.M{"username": userName}).Decode(&user)
-	if err != nil {
-		log.Printf("❌ Erreur récupération utilisateur: %v\n", err)
-		return nil, err
-	}
-
-	log.Printf("✅ Utilisateur trouvé: %v", user)
-
-	var price int = number_pull * 100
-	if number_pull == 10 {
-		price = 900
-	}
-
-	if user.Coins < price {
-		log.Printf("❌ Pas assez de pièces: %d disponibles, %d nécessaires\n", user.Coins, price)
-		return nil, errors.New("Pas assez de pièces")
-	}
-
-	var result []int
-
-	// Mise à jour de l'inventaire
-	for i := 0; i < number_pull; i++ {
-		randomValue := rand.Float64()
-		var rarity int
-		if randomValue <= 0.05 {
-			rarity = 6
-		} else if randomValue <= 0.1 {
-			rarity = 5
-		} else if randomValue <= 0.2 {
-			rarity = 4
-		} else {
-			rarity = 3
-		}
-
-		for j := range user.Inventory {
-			if user.Inventory[j].Rarity == rarity {
-				user.Inventory[j].Quantity++
-				result = append(result, rarity)
-				break
-			}
-		}
-	}
-
-	user.Coins -= price
-	log.Printf("💰 Mise à jour des pièces: %d", user.Coins)
-
-	_, err = coll.UpdateOne(
-		context.TODO(),
-		bson.M{"username": userName},
-		bson.M{
-			"$set": bson.M{
-				"coins":     user.Coins,
-				"inventory": user.Inventory,
-			},
-		},
-	)
-	if err != nil {
-		log.Printf("❌ Erreur mise à jour MongoDB: %v\n", err)
-	}
-
-	log.Printf(userName)
-	log.Printf("✅ Inventaire mis à jour: %v", user.Inventory)
-	return result, err
-}
-
-func GetUserCategories(client *mongo.Client, username string) ([]model.Category, error) {
-	collection := client.Database("DB").Collection("categories")
+func (s *MongoStore) GetUserCategories(ctx context.Context, username string, includeHidden bool) ([]model.Category, error) {
+	collection := s.db.Collection("categories")
 
-	// Définir le filtre
-	var filter bson.M
+	// Définir le filtre, un filtre vide récupère toutes les catégories
+	filter := bson.M{}
 	if username != "" {
-		filter = bson.M{"username": username}
-	} else {
-		filter = bson.M{} // Filtre vide pour récupérer toutes les catégories
+		filter["username"] = username
+	}
+	if !includeHidden {
+		filter["hidden"] = bson.M{"$ne": true}
 	}
 
 	// Trouver les documents
-	cursor, err := collection.Find(context.TODO(), filter)
+	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
 		log.Printf("Erreur lors de la recherche des catégories : %v", err)
 		return nil, err
 	}
-	defer cursor.Close(context.TODO())
+	defer cursor.Close(ctx)
 
 	// Convertir les résultats en slice
 	var categories []model.Category
-	if err = cursor.All(context.TODO(), &categories); err != nil {
+	if err = cursor.All(ctx, &categories); err != nil {
 		log.Printf("Erreur lors du décodage des catégories : %v", err)
 		return nil, err
 	}
@@ -536,18 +1190,49 @@ func GetUserCategories(client *mongo.Client, username string) ([]model.Category,
 	return categories, nil
 }
 
-func CategoryExists(client *mongo.Client, categoryName string) (bool, error) {
-	collection := client.Database("DB").Collection("categories")
+func (s *MongoStore) CategoryExists(ctx context.Context, categoryName string) (bool, error) {
+	collection := s.db.Collection("categories")
 	filter := bson.M{"categoryname": categoryName}
-	count, err := collection.CountDocuments(context.TODO(), filter)
+	count, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func CreateCategory(client *mongo.Client, username string, categoryName string, questions []model.Question) error {
-	collection := client.Database("DB").Collection("categories")
+// SetCategoryHidden masque ou rend visible une catégorie
+func (s *MongoStore) SetCategoryHidden(ctx context.Context, categoryName string, hidden bool) error {
+	result, err := s.db.Collection("categories").UpdateOne(ctx, bson.M{"categoryname": categoryName}, bson.M{"$set": bson.M{"hidden": hidden}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteCategory supprime une catégorie et ses questions
+func (s *MongoStore) DeleteCategory(ctx context.Context, categoryName string) error {
+	result, err := s.db.Collection("categories").DeleteOne(ctx, bson.M{"categoryname": categoryName})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetCategoryByName recherche une catégorie par son nom
+func (s *MongoStore) GetCategoryByName(ctx context.Context, categoryName string) (model.Category, error) {
+	var category model.Category
+	err := s.db.Collection("categories").FindOne(ctx, bson.M{"categoryname": categoryName}).Decode(&category)
+	return category, notFound(err)
+}
+
+func (s *MongoStore) CreateCategory(ctx context.Context, username string, categoryName string, questions []model.Question) error {
+	collection := s.db.Collection("categories")
 
 	category := bson.M{
 		"username":     username,
@@ -555,12 +1240,12 @@ func CreateCategory(client *mongo.Client, username string, categoryName string,
 		"questions":    questions,
 	}
 
-	_, err := collection.InsertOne(context.TODO(), category)
+	_, err := collection.InsertOne(ctx, category)
 	return err
 }
 
-func UpdateCategory(client *mongo.Client, username, currentCategoryName, newCategoryName string, questions []model.Question) error {
-	collection := client.Database("DB").Collection("categories")
+func (s *MongoStore) UpdateCategory(ctx context.Context, username, currentCategoryName, newCategoryName string, questions []model.Question) error {
+	collection := s.db.Collection("categories")
 
 	filter := bson.M{
 		"username":     username,
@@ -578,81 +1263,150 @@ func UpdateCategory(client *mongo.Client, username, currentCategoryName, newCate
 		update["$set"].(bson.M)["categoryname"] = newCategoryName
 	}
 
-	result, err := collection.UpdateOne(context.Background(), filter, update)
+	result, err := collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
 	}
 	if result.MatchedCount == 0 {
 		log.Printf("❌ Aucune catégorie trouvée pour la mise à jour")
-		return err
+		return ErrNotFound
 	}
 
 	return nil
 }
 
-func UseCheatSheet(client *mongo.Client, quizID string, rarity int) ([]string, error) {
-	coll := client.Database("DB").Collection("Quiz")
-
-	// Récupérer le quiz
-	objID, err := primitive.ObjectIDFromHex(quizID)
-	if err != nil {
-		log.Printf("❌ Erreur lors de la conversion de l'ID du quiz en ObjectID : %v\n", err)
-		return nil, err
+// ================== Fonctions pour la réserve de questions ==================
+
+// AddPoolQuestions ajoute les questions absentes de la réserve et retourne le nombre de questions ajoutées
+func (s *MongoStore) AddPoolQuestions(ctx context.Context, questions []model.PoolQuestion) (int, error) {
+	coll := s.db.Collection("question_pool")
+	added := 0
+	for _, question := range questions {
+		result, err := coll.UpdateOne(
+			ctx,
+			bson.M{"question.question_text": question.Question.QuestionText},
+			bson.M{"$setOnInsert": question},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return added, err
+		}
+		if result.UpsertedCount > 0 {
+			added++
+		}
 	}
+	return added, nil
+}
 
-	var quiz model.Quiz
-	err = coll.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&quiz)
-	if err != nil {
-		log.Printf("❌ Erreur lors de la récupération du quiz : %v\n", err)
-		return nil, err
+// filtre de la réserve pour une catégorie, une difficulté et un type
+func poolFilter(category, difficulty, questionType string) bson.M {
+	filter := bson.M{"category": category}
+	if difficulty != "" && difficulty != model.DifficultyAny {
+		filter["difficulty"] = difficulty
 	}
+	if questionType != "" {
+		filter["type"] = questionType
+	}
+	return filter
+}
 
-	var hints int = 0
-	if (rarity) == 6 {
+// CountPoolQuestions compte les questions disponibles dans la réserve
+func (s *MongoStore) CountPoolQuestions(ctx context.Context, category, difficulty, questionType string) (int, error) {
+	count, err := s.db.Collection("question_pool").CountDocuments(ctx, poolFilter(category, difficulty, questionType))
+	return int(count), err
+}
 
+// SamplePoolQuestions tire au hasard jusqu'à n questions de la réserve absentes de exclude
+func (s *MongoStore) SamplePoolQuestions(ctx context.Context, category, difficulty, questionType string, exclude []string, n int) ([]model.PoolQuestion, error) {
+	filter := poolFilter(category, difficulty, questionType)
+	if len(exclude) > 0 {
+		filter["question.question_text"] = bson.M{"$nin": exclude}
 	}
-	if rarity == 5 {
-		hints = 3
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sample", Value: bson.M{"size": n}}},
 	}
-	if rarity == 4 {
-		hints = 2
+	cursor, err := s.db.Collection("question_pool").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
 	}
-	if rarity == 3 {
-		hints = 1
+	defer cursor.Close(ctx)
+
+	var questions []model.PoolQuestion
+	if err = cursor.All(ctx, &questions); err != nil {
+		return nil, err
 	}
+	return questions, nil
+}
 
-	var allHints []string
-	currentQuestion := quiz.Questions[quiz.Number_question]
-	for _, response := range currentQuestion.Responses {
-		if response != currentQuestion.ResponseCorrect {
-			allHints = append(allHints, response)
-		}
+// ================== Fonctions pour les explications ==================
+
+// GetExplanation retourne l'explication en cache d'une question
+func (s *MongoStore) GetExplanation(ctx context.Context, key string) (model.Explanation, error) {
+	var explanation model.Explanation
+	err := s.db.Collection("explanations").FindOne(ctx, bson.M{"_id": key}).Decode(&explanation)
+	if err == mongo.ErrNoDocuments {
+		return explanation, ErrNotFound
 	}
+	return explanation, err
+}
+
+// SaveExplanation enregistre ou remplace l'explication d'une question
+func (s *MongoStore) SaveExplanation(ctx context.Context, explanation model.Explanation) error {
+	_, err := s.db.Collection("explanations").ReplaceOne(ctx, bson.M{"_id": explanation.Key}, explanation, options.Replace().SetUpsert(true))
+	return err
+}
 
-	// Mélanger les réponses
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(len(allHints), func(i, j int) { allHints[i], allHints[j] = allHints[j], allHints[i] })
+// ================== Fonctions pour les bannières ==================
 
-	// Sélectionner le nombre d'indices requis
-	var result []string
-	for i := 0; i < hints && i < len(allHints); i++ {
-		result = append(result, allHints[i])
+// ListBanners retourne toutes les bannières enregistrées, ouvertes ou non
+func (s *MongoStore) ListBanners(ctx context.Context) ([]model.Banner, error) {
+	cursor, err := s.db.Collection("banners").Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	//Mettre a jour les cheatsheets de l'user
-	userColl := client.Database("DB").Collection("users")
-	filter := bson.M{"username": quiz.Username, "inventory.rarity": rarity}
-	update := bson.M{
-		"$inc": bson.M{
-			"inventory.$.quantity":    -1,
-			"stats.used_cheat_sheets": 1,
-		},
+	banners := []model.Banner{}
+	if err = cursor.All(ctx, &banners); err != nil {
+		return nil, err
 	}
-	_, err = userColl.UpdateOne(context.TODO(), filter, update)
+	return banners, nil
+}
+
+// GetBanner retourne la bannière bannerID
+func (s *MongoStore) GetBanner(ctx context.Context, bannerID string) (model.Banner, error) {
+	var banner model.Banner
+	err := s.db.Collection("banners").FindOne(ctx, bson.M{"_id": bannerID}).Decode(&banner)
+	return banner, notFound(err)
+}
+
+// SaveBanner crée ou remplace une bannière
+func (s *MongoStore) SaveBanner(ctx context.Context, banner model.Banner) error {
+	_, err := s.db.Collection("banners").ReplaceOne(ctx, bson.M{"_id": banner.ID}, banner, options.Replace().SetUpsert(true))
+	return err
+}
+
+// ================== Fonctions pour le journal d'audit ==================
+
+// InsertAuditEntry ajoute une entrée au journal d'audit
+func (s *MongoStore) InsertAuditEntry(ctx context.Context, entry model.AuditEntry) error {
+	_, err := s.db.Collection("audit").InsertOne(ctx, entry)
+	return err
+}
+
+// ListAuditEntries retourne les entrées du journal, de la plus récente à la plus ancienne
+func (s *MongoStore) ListAuditEntries(ctx context.Context, skip int, limit int) ([]model.AuditEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetSkip(int64(skip)).SetLimit(int64(limit))
+	cursor, err := s.db.Collection("audit").Find(ctx, bson.M{}, opts)
 	if err != nil {
-		log.Printf("❌ Erreur lors de la mise à jour de l'inventaire de l'utilisateur : %v\n", err)
 		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	return result, nil
+	var entries []model.AuditEntry
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
 }
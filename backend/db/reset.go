@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"quizmaster/auth"
+	"quizmaster/config"
+	"quizmaster/model"
+	"time"
+)
+
+// PasswordResetTTL est la durée de validité d'un lien de réinitialisation (PASSWORD_RESET_TTL, 1h par défaut)
+func PasswordResetTTL() time.Duration {
+	return config.Duration("PASSWORD_RESET_TTL", time.Hour)
+}
+
+// NewPasswordReset crée une demande de réinitialisation pour l'utilisateur et retourne le token à lui envoyer.
+// Les demandes précédentes de l'utilisateur sont annulées.
+func NewPasswordReset(ctx context.Context, store Store, userID string) (string, time.Time, error) {
+	token, err := auth.NewToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err = store.DeleteUserPasswordResets(ctx, userID); err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	reset := model.PasswordReset{
+		UserID:    userID,
+		TokenHash: auth.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(PasswordResetTTL()),
+	}
+	if _, err = store.CreatePasswordReset(ctx, reset); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, reset.ExpiresAt, nil
+}
+
+// ResetPassword remplace le mot de passe de l'utilisateur associé au token de réinitialisation.
+// Le token n'est utilisable qu'une fois et toutes les sessions de l'utilisateur sont révoquées.
+// Retourne une *auth.PasswordPolicyError si le nouveau mot de passe est refusé.
+func ResetPassword(ctx context.Context, store Store, token, newPassword string) (model.User, error) {
+	reset, err := store.GetPasswordResetByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		if err == ErrNotFound {
+			return model.User{}, ErrInvalidToken
+		}
+		return model.User{}, err
+	}
+	if reset.UsedAt != nil {
+		return model.User{}, ErrInvalidToken
+	}
+	if time.Now().After(reset.ExpiresAt) {
+		return model.User{}, ErrExpiredToken
+	}
+
+	user, err := store.GetUserByID(ctx, reset.UserID)
+	if err != nil {
+		if err == ErrNotFound {
+			return model.User{}, ErrInvalidToken
+		}
+		return model.User{}, err
+	}
+	// le token n'est pas consommé si le mot de passe est refusé, l'utilisateur peut réessayer
+	if err = auth.ValidatePassword(newPassword, user.Username); err != nil {
+		return user, err
+	}
+	hashedPassword, err := auth.HashPassword(newPassword)
+	if err != nil {
+		return user, err
+	}
+
+	if err = store.UsePasswordReset(ctx, reset.ID); err != nil {
+		if err == ErrNotFound {
+			// le token vient d'être utilisé par une autre requête
+			return user, ErrInvalidToken
+		}
+		return user, err
+	}
+	if err = store.UpdateUserPassword(ctx, user.ID, hashedPassword); err != nil {
+		return user, err
+	}
+
+	if _, err = store.DeleteUserSessions(ctx, user.ID); err != nil {
+		return user, err
+	}
+	return user, store.DeleteUserPasswordResets(ctx, user.ID)
+}
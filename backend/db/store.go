@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"quizmaster/model"
+	"time"
+)
+
+// erreurs communes à toutes les implémentations du Store
+var (
+	ErrNotFound = errors.New("document introuvable")
+	ErrNoChange = errors.New("aucune mise à jour effectuée")
+	// le solde (pièces ou antisèches) ne permet pas l'opération
+	ErrInsufficientBalance = errors.New("solde insuffisant")
+	// le document a été modifié par une autre requête depuis sa lecture
+	ErrConflict = errors.New("document modifié entre-temps")
+)
+
+// Store regroupe toutes les opérations de persistance utilisées par les handlers.
+// MongoStore est l'implémentation utilisée en production, MemoryStore sert pour
+// les tests et les démonstrations locales sans MongoDB.
+type Store interface {
+	// Utilisateurs
+	InsertUser(ctx context.Context, user model.User) (string, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	GetAllUsers(ctx context.Context) ([]model.User, error)
+	GetUserByID(ctx context.Context, userID string) (model.User, error)
+	GetUserByName(ctx context.Context, username string) (model.User, error)
+	GetTopPlayers(ctx context.Context, limit int) ([]model.User, error)
+	SetUserPicture(ctx context.Context, userID string, picture string) error
+	DeleteUser(ctx context.Context, userID string) error
+	UpdateUserUsername(ctx context.Context, userID string, newUsername string) error
+	UpdateUserPassword(ctx context.Context, userID string, newPassword string) error
+	// UpdateUser enregistre l'expérience, les pièces et l'inventaire passent par les mouvements du journal
+	UpdateUser(ctx context.Context, user model.User) error
+	SetUserRole(ctx context.Context, userID string, role string) error
+	SetUserBanned(ctx context.Context, userID string, banned bool) error
+	SetUserEmail(ctx context.Context, userID string, email string) error
+	SetUserOpenTDBToken(ctx context.Context, userID string, token string) error
+
+	// Sessions
+	CreateSession(ctx context.Context, session model.Session) (model.Session, error)
+	GetSessionByAccessHash(ctx context.Context, accessHash string) (model.Session, error)
+	GetSessionByRefreshHash(ctx context.Context, refreshHash string) (model.Session, error)
+	RotateSession(ctx context.Context, oldRefreshHash string, next model.Session) error
+	DeleteSession(ctx context.Context, sessionID string) error
+	DeleteUserSessions(ctx context.Context, userID string) (int, error)
+	DeleteOtherSessions(ctx context.Context, userID string, keepSessionID string) (int, error)
+
+	// Réinitialisation de mot de passe
+	CreatePasswordReset(ctx context.Context, reset model.PasswordReset) (model.PasswordReset, error)
+	GetPasswordResetByHash(ctx context.Context, tokenHash string) (model.PasswordReset, error)
+	UsePasswordReset(ctx context.Context, resetID string) error
+	DeleteUserPasswordResets(ctx context.Context, userID string) error
+
+	// Inventaire. Chaque modification des pièces ou des antisèches est inscrite au journal.
+	// PullCheatSheets débite price pièces et ajoute les antisèches tirées sur la bannière bannerID
+	// (quantité par rareté) en une seule opération. Retourne ErrInsufficientBalance si le solde ne suffit pas.
+	PullCheatSheets(ctx context.Context, username string, bannerID string, price int, pulled map[int]int) (model.User, error)
+	// ConsumeCheatSheet retire une antisèche utilisée sur le quiz quizID, ErrInsufficientBalance s'il n'en reste pas
+	ConsumeCheatSheet(ctx context.Context, username string, rarity int, quizID string) error
+	AdjustCoins(ctx context.Context, userID string, delta int, reason string) (model.User, error)
+	AdjustInventory(ctx context.Context, userID string, rarity int, delta int, reason string) (model.User, error)
+	// CreditQuizRewards crédite en une seule opération les récompenses du quiz quizID à l'utilisateur.
+	// Retourne ErrNoChange si elles l'ont déjà été (récompense déjà inscrite au journal).
+	CreditQuizRewards(ctx context.Context, username string, quizID string, reward model.QuizReward) error
+	MarkQuizRewarded(ctx context.Context, quizID string) error
+	// ListUnrewardedQuizzes liste les quiz terminés avant before dont les récompenses n'ont pas été créditées
+	ListUnrewardedQuizzes(ctx context.Context, before time.Time, limit int) ([]model.Quiz, error)
+	AddAbandonedQuiz(ctx context.Context, username string) error
+
+	// Bannières de tirage
+	ListBanners(ctx context.Context) ([]model.Banner, error)
+	GetBanner(ctx context.Context, bannerID string) (model.Banner, error)
+	// SaveBanner crée ou remplace une bannière
+	SaveBanner(ctx context.Context, banner model.Banner) error
+
+	// Journal des pièces et antisèches
+	// ListLedgerEntries retourne les mouvements de l'utilisateur, du plus récent au plus ancien
+	ListLedgerEntries(ctx context.Context, userID string, skip int, limit int) ([]model.LedgerEntry, error)
+	// UserLedger retourne tous les mouvements de l'utilisateur dans l'ordre
+	UserLedger(ctx context.Context, userID string) ([]model.LedgerEntry, error)
+
+	// Catégories
+	GetUserCategories(ctx context.Context, username string, includeHidden bool) ([]model.Category, error)
+	CategoryExists(ctx context.Context, categoryName string) (bool, error)
+	GetCategoryByName(ctx context.Context, categoryName string) (model.Category, error)
+	CreateCategory(ctx context.Context, username string, categoryName string, questions []model.Question) error
+	UpdateCategory(ctx context.Context, username, currentCategoryName, newCategoryName string, questions []model.Question) error
+	CreateQuestion(ctx context.Context, username string, categoryName string, question model.Question) error
+	ExistQuestion(ctx context.Context, username string, categoryName string, question model.Question) (bool, model.Question, error)
+	GetQuestionsByCategory(ctx context.Context, categoryName string) []model.Question
+	SetCategoryHidden(ctx context.Context, categoryName string, hidden bool) error
+	DeleteCategory(ctx context.Context, categoryName string) error
+
+	// Quiz
+	// quiz en cours d'un utilisateur pour un mode et une catégorie
+	OnGoingQuiz(ctx context.Context, username string, mode string, category string) (bool, model.Quiz)
+	ListOnGoingQuizzes(ctx context.Context, username string) ([]model.Quiz, error)
+	CreateQuiz(ctx context.Context, quiz model.Quiz) (model.Quiz, error)
+	GetQuizByID(ctx context.Context, quizID string) (model.Quiz, error)
+	// UpdateQuiz enregistre le quiz s'il est toujours à la version quiz.Version, ErrConflict sinon,
+	// ErrNotFound si le quiz n'existe pas
+	UpdateQuiz(ctx context.Context, quiz model.Quiz) error
+	// quiz terminés d'un utilisateur, du plus récent au plus ancien
+	ListUserQuizzes(ctx context.Context, username string, skip int, limit int) ([]model.Quiz, error)
+	// quiz en cours sans activité depuis before
+	ListStaleQuizzes(ctx context.Context, before time.Time, limit int) ([]model.Quiz, error)
+	// termine un quiz encore en cours avec l'état status, ErrNoChange s'il était déjà terminé
+	CloseQuiz(ctx context.Context, quizID string, status string, at time.Time) error
+	// SeenQuestions retourne le texte des questions déjà servies à l'utilisateur dans les quiz du mode et de la catégorie
+	SeenQuestions(ctx context.Context, username string, mode string, category string) ([]string, error)
+
+	// Réserve locale de questions Open Trivia DB.
+	// Une difficulté ou un type vide (ou "any") ne filtre pas.
+	AddPoolQuestions(ctx context.Context, questions []model.PoolQuestion) (int, error)
+	CountPoolQuestions(ctx context.Context, category, difficulty, questionType string) (int, error)
+	// SamplePoolQuestions tire au hasard jusqu'à n questions dont le texte n'est pas dans exclude
+	SamplePoolQuestions(ctx context.Context, category, difficulty, questionType string, exclude []string, n int) ([]model.PoolQuestion, error)
+
+	// Explications générées des questions Open Trivia DB
+	GetExplanation(ctx context.Context, key string) (model.Explanation, error)
+	SaveExplanation(ctx context.Context, explanation model.Explanation) error
+
+	// Journal d'audit
+	InsertAuditEntry(ctx context.Context, entry model.AuditEntry) error
+	ListAuditEntries(ctx context.Context, skip int, limit int) ([]model.AuditEntry, error)
+
+	Close() error
+}
+
+// Open choisit l'implémentation du Store selon la variable STORE ("mongo" par défaut, ou "memory")
+func Open(ctx context.Context) (Store, error) {
+	switch backend := os.Getenv("STORE"); backend {
+	case "", "mongo":
+		uri := os.Getenv("MONGO_URI")
+		if uri == "" {
+			return nil, errors.New("MONGO_URI est vide ou non défini")
+		}
+		client, err := Connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		store := NewMongoStore(client, databaseName())
+		if err = store.EnsureIndexes(ctx); err != nil {
+			store.Close()
+			return nil, err
+		}
+		return store, nil
+	case "memory":
+		log.Println("Utilisation du stockage en mémoire, les données seront perdues à l'arrêt")
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("STORE inconnu : %q (valeurs possibles : mongo, memory)", backend)
+	}
+}
+
+// nom de la base MongoDB, "DB" par défaut
+func databaseName() string {
+	if name := os.Getenv("MONGO_DATABASE"); name != "" {
+		return name
+	}
+	return "DB"
+}
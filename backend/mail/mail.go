@@ -0,0 +1,132 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"quizmaster/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message est un e-mail en texte brut
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// valeurs sensibles du corps (tokens), masquées par LogMailer
+	Secrets []string
+}
+
+// Mailer envoie des e-mails, l'implémentation est choisie par MAIL_TRANSPORT
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv construit le Mailer configuré par MAIL_TRANSPORT :
+// "smtp" (SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD, MAIL_FROM),
+// "file" (MAIL_FILE) ou "log" (développement local uniquement, avec MAIL_LOG_DEV=true).
+// Sans MAIL_TRANSPORT, aucun e-mail n'est envoyé et le Mailer retourné est nil.
+func FromEnv() (Mailer, error) {
+	switch transport := config.String("MAIL_TRANSPORT", ""); transport {
+	case "":
+		return nil, nil
+	case "smtp":
+		return &SMTPMailer{
+			Host:     config.String("SMTP_HOST", "localhost"),
+			Port:     config.Int("SMTP_PORT", 587),
+			Username: config.String("SMTP_USERNAME", ""),
+			Password: config.String("SMTP_PASSWORD", ""),
+			From:     config.String("MAIL_FROM", "no-reply@quizmaster.local"),
+		}, nil
+	case "file":
+		return &FileMailer{Path: config.String("MAIL_FILE", "mails.log")}, nil
+	case "log":
+		// les logs sont souvent partagés : ce transport doit être demandé explicitement
+		if !config.Bool("MAIL_LOG_DEV", false) {
+			return nil, fmt.Errorf("MAIL_TRANSPORT=log est réservé au développement, définir MAIL_LOG_DEV=true")
+		}
+		return LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("MAIL_TRANSPORT inconnu : %q", transport)
+	}
+}
+
+// format texte d'un message, utilisé par les implémentations de développement
+func (m Message) String() string {
+	return fmt.Sprintf("À : %s\nSujet : %s\n\n%s\n", m.To, m.Subject, m.Body)
+}
+
+// SMTPMailer envoie les e-mails par un serveur SMTP
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	// les en-têtes ne doivent pas pouvoir être injectés par le destinataire ou le sujet
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("en-tête d'e-mail invalide")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	body := "From: " + m.From + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\n" +
+		msg.Body
+
+	// smtp.SendMail ne prend pas de contexte, on abandonne l'attente si la requête est annulée
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(fmt.Sprintf("%s:%d", m.Host, m.Port), auth, m.From, []string{msg.To}, []byte(body))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LogMailer écrit les e-mails dans les logs du serveur, sans leurs valeurs sensibles
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	for _, secret := range msg.Secrets {
+		if secret != "" {
+			msg.Body = strings.ReplaceAll(msg.Body, secret, "[masqué]")
+		}
+	}
+	log.Printf("E-mail (non envoyé) :\n%s", msg)
+	return nil
+}
+
+// FileMailer ajoute les e-mails à la fin d'un fichier
+type FileMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "==== %s ====\n%s\n", time.Now().Format(time.RFC3339), msg)
+	return err
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Mailer
+		wantErr bool
+	}{
+		{"sans transport", map[string]string{"MAIL_TRANSPORT": ""}, nil, false},
+		{"fichier", map[string]string{"MAIL_TRANSPORT": "file", "MAIL_FILE": "mails.log"}, &FileMailer{Path: "mails.log"}, false},
+		{"logs sans MAIL_LOG_DEV", map[string]string{"MAIL_TRANSPORT": "log"}, nil, true},
+		{"logs en développement", map[string]string{"MAIL_TRANSPORT": "log", "MAIL_LOG_DEV": "true"}, LogMailer{}, false},
+		{"transport inconnu", map[string]string{"MAIL_TRANSPORT": "pigeon"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			got, err := FromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("erreur %v, attendue : %v", err, tt.wantErr)
+			}
+			if tt.want == nil && got != nil {
+				t.Errorf("Mailer %#v, attendu aucun", got)
+			}
+			if file, ok := tt.want.(*FileMailer); ok {
+				if got, ok := got.(*FileMailer); !ok || got.Path != file.Path {
+					t.Errorf("Mailer %#v, attendu %#v", got, file)
+				}
+			} else if tt.want != nil && got != tt.want {
+				t.Errorf("Mailer %#v, attendu %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogMailerRedactsSecrets(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	msg := Message{To: "alice@example.com", Subject: "Test", Body: "lien : https://example.com/?token=s3cret", Secrets: []string{"s3cret"}}
+	if err := (LogMailer{}).Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send : %v", err)
+	}
+	if strings.Contains(out.String(), "s3cret") || !strings.Contains(out.String(), "[masqué]") {
+		t.Errorf("secret présent dans les logs : %s", out.String())
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mails.log")
+	mailer := &FileMailer{Path: path}
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		if err := mailer.Send(context.Background(), Message{To: to, Subject: "Test", Body: "Bonjour"}); err != nil {
+			t.Fatalf("Send : %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "alice@example.com") || !strings.Contains(string(data), "bob@example.com") {
+		t.Errorf("contenu du fichier : %q (%v)", data, err)
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer := &SMTPMailer{Host: "localhost", Port: 1}
+	err := mailer.Send(context.Background(), Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Test"})
+	if err == nil {
+		t.Error("destinataire avec retour à la ligne accepté")
+	}
+}
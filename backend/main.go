@@ -4,9 +4,9 @@ import (
 	"context"
 	"log"
 	"net/http"
-	"os"
 	"quizmaster/api"
 	"quizmaster/db"
+	"quizmaster/mail"
 
 	"github.com/gorilla/handlers"
 	"github.com/joho/godotenv"
@@ -18,24 +18,36 @@ func main() {
 		log.Fatal("Erreur lors du chargement du fichier .env")
 	}
 
-	uri := os.Getenv("MONGO_URI")
-	if uri == "" {
-		log.Fatal("MONGO_URI est vide ou non défini")
+	// Choix du stockage (MongoDB ou mémoire) selon la configuration
+	store, err := db.Open(context.Background())
+	if err != nil {
+		log.Fatal(err)
 	}
+	defer store.Close()
 
-	api.ConfigureRoutes()
-	log.Println("Server starting on port 8080...")
-	router := api.ConfigureRoutes()
+	// Les comptes listés dans ADMIN_USERNAMES reçoivent le rôle admin
+	if err = db.EnsureAdmins(context.Background(), store); err != nil {
+		log.Printf("Erreur lors de l'attribution des rôles admin : %v", err)
+	}
 
-	client := db.Connect()
+	// Sans transport d'e-mails, la réinitialisation du mot de passe est désactivée
+	mailer, err := mail.FromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if mailer == nil {
+		log.Println("MAIL_TRANSPORT non défini, la réinitialisation du mot de passe est désactivée")
+	}
 
-	defer client.Disconnect(context.TODO())
+	log.Println("Server starting on port 8080...")
+	router := api.ConfigureRoutes(store, mailer)
+	api.StartJobs(context.Background())
 
 	// Pour éviter les problèmes de CORS
 	corsOpts := handlers.CORS(
 		handlers.AllowedOrigins([]string{"*"}),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization"}),
+		handlers.AllowedHeaders([]string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "Idempotency-Key"}),
 		handlers.AllowCredentials(),
 	)
 	if err := http.ListenAndServe("0.0.0.0:8080", corsOpts(router)); err != nil {
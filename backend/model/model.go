@@ -1,15 +1,76 @@
 package model
 
+import "time"
+
 type User struct {
 	ID         string       `bson:"_id,omitempty"`
 	Username   string       `bson:"username"`
-	Password   string       `bson:"password"`
-	Token      string       `bson:"token"`
+	Password   string       `bson:"password" json:"-"`
+	Email      string       `bson:"email"`
 	Experience int          `bson:"experience"`
 	Coins      int          `bson:"coins"`
 	Picture    string       `bson:"picture"`
 	Inventory  []CheatSheet `bson:"inventory"`
 	Stats      Stats        `bson:"stats"`
+	Role       string       `bson:"role"`
+	Banned     bool         `bson:"banned"`
+	// token de session Open Trivia DB, évite de resservir les mêmes questions
+	OpenTDBToken string `bson:"opentdb_token" json:"-"`
+	// numéro du dernier mouvement de pièces ou d'antisèches inscrit au journal
+	LedgerSeq int `bson:"ledger_seq" json:"-"`
+}
+
+// rôles des utilisateurs, du moins au plus privilégié
+const (
+	RolePlayer    = "player"
+	RoleCreator   = "creator"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RolePlayer:    0,
+	RoleCreator:   1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+// ValidRole indique si role est un rôle connu
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleRank retourne le niveau de privilège du rôle, un rôle vide ou inconnu vaut "player"
+func RoleRank(role string) int {
+	return roleRanks[role]
+}
+
+// HasRole indique si l'utilisateur a au moins les privilèges du rôle donné
+func (u User) HasRole(role string) bool {
+	return RoleRank(u.Role) >= RoleRank(role)
+}
+
+// Session représente une connexion d'un utilisateur, seules les empreintes des tokens sont stockées
+type Session struct {
+	ID               string    `json:"id" bson:"_id,omitempty"`
+	UserID           string    `json:"userID" bson:"user_id"`
+	AccessHash       string    `json:"-" bson:"access_hash"`
+	RefreshHash      string    `json:"-" bson:"refresh_hash"`
+	CreatedAt        time.Time `json:"createdAt" bson:"created_at"`
+	ExpiresAt        time.Time `json:"expiresAt" bson:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt" bson:"refresh_expires_at"`
+}
+
+// PasswordReset est une demande de réinitialisation de mot de passe, à usage unique.
+// Seule l'empreinte du token envoyé par e-mail est stockée.
+type PasswordReset struct {
+	ID        string     `json:"id" bson:"_id,omitempty"`
+	UserID    string     `json:"userID" bson:"user_id"`
+	TokenHash string     `json:"-" bson:"token_hash"`
+	CreatedAt time.Time  `json:"createdAt" bson:"created_at"`
+	ExpiresAt time.Time  `json:"expiresAt" bson:"expires_at"`
+	UsedAt    *time.Time `json:"usedAt,omitempty" bson:"used_at,omitempty"`
 }
 
 type Stats struct {
@@ -17,6 +78,8 @@ type Stats struct {
 	CorrectResponses int `bson:"correct_responses" json:"correct_responses"`
 	FullMarks        int `bson:"full_marks" json:"full_marks"`
 	UsedCheatSheets  int `bson:"used_cheat_sheets" json:"used_cheat_sheets"`
+	// quiz abandonnés par le joueur ou expirés faute d'activité
+	AbandonedQuizzes int `bson:"abandoned_quizzes" json:"abandoned_quizzes"`
 }
 
 type CheatSheet struct {
@@ -24,10 +87,39 @@ type CheatSheet struct {
 	Quantity int `bson:"quantity" json:"quantity"`
 }
 
+// Banner est une bannière de tirages d'antisèches, avec ses probabilités et ses prix
+type Banner struct {
+	// identifiant choisi par l'administrateur, ex : "standard"
+	ID   string `json:"id" bson:"_id"`
+	Name string `json:"name" bson:"name"`
+	// poids de chaque rareté, les probabilités publiées en sont déduites
+	Weights []RarityWeight `json:"weights" bson:"weights"`
+	// prix selon le nombre de tirages, un tirage multiple peut coûter moins cher
+	Pulls []PullOption `json:"pulls" bson:"pulls"`
+	// période d'ouverture, sans limite si absente
+	StartsAt *time.Time `json:"startsAt,omitempty" bson:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty" bson:"ends_at,omitempty"`
+	// raretés mises en avant sur la bannière
+	Featured []int `json:"featured,omitempty" bson:"featured,omitempty"`
+}
+
+// RarityWeight est le poids d'une rareté dans le tirage
+type RarityWeight struct {
+	Rarity int     `json:"rarity" bson:"rarity"`
+	Weight float64 `json:"weight" bson:"weight"`
+}
+
+// PullOption est le prix d'un tirage de Quantity antisèches
+type PullOption struct {
+	Quantity int `json:"quantity" bson:"quantity"`
+	Price    int `json:"price" bson:"price"`
+}
+
 type Category struct {
 	Username     string     `json:"Username" bson:"username"`
 	CategoryName string     `json:"CategoryName" bson:"categoryname"`
 	Questions    []Question `json:"Questions" bson:"questions"`
+	Hidden       bool       `json:"Hidden" bson:"hidden"`
 }
 
 type Quiz struct {
@@ -37,14 +129,187 @@ type Quiz struct {
 	Mark            int        `bson:"mark"`
 	Finish          bool       `bson:"finish"`
 	Number_question int        `bson:"number_question"`
+	// incrémentée à chaque mise à jour, pour détecter les modifications concurrentes
+	Version int `bson:"version"`
+	// état du quiz, un quiz abandonné ou expiré est aussi terminé (Finish)
+	Status string `bson:"status"`
+	// un joueur peut avoir un quiz en cours par mode et par catégorie
+	Mode       string `bson:"mode"`
+	Category   string `bson:"category"`
+	Amount     int    `bson:"amount"`
+	Difficulty string `bson:"difficulty"`
+	Type       string `bson:"type"`
+	// temps accordé pour chaque question, en secondes (0 : pas de limite)
+	TimeLimit int            `bson:"time_limit"`
+	Answers   []AnswerRecord `bson:"answers"`
+	// règle de calcul des points ("classic" par défaut ou "competitive") et total obtenu
+	Scoring string `bson:"scoring"`
+	Points  int    `bson:"points"`
+	// nombre d'antisèches utilisées pendant le quiz
+	CheatSheetsUsed int       `bson:"cheat_sheets_used"`
+	CreatedAt       time.Time `bson:"created_at"`
+	// dernière activité (réponse, antisèche), pour l'expiration des quiz délaissés
+	UpdatedAt  time.Time  `bson:"updated_at"`
+	FinishedAt *time.Time `bson:"finished_at,omitempty"`
+	// récompenses créditées au joueur. Les quiz créés avant ce suivi n'ont pas le champ
+	// et ne sont pas repris par la réconciliation.
+	RewardsApplied bool `bson:"rewards_applied" json:"-"`
+}
+
+// QuizReward est ce que rapporte un quiz terminé à son joueur
+type QuizReward struct {
+	Coins            int
+	Experience       int
+	CorrectResponses int
+	FullMark         bool
 }
 
+// AnswerRecord suit une question servie au joueur
+type AnswerRecord struct {
+	ServedAt   time.Time  `bson:"served_at" json:"servedAt"`
+	AnsweredAt *time.Time `bson:"answered_at,omitempty" json:"answeredAt,omitempty"`
+	// réponse arrivée après la limite de temps, comptée fausse ou passée selon QUIZ_LATE_ANSWERS
+	Late    bool    `bson:"late" json:"late"`
+	Skipped bool    `bson:"skipped" json:"skipped"`
+	Correct bool    `bson:"correct" json:"correct"`
+	Credit  float64 `bson:"credit" json:"credit"`
+	// clé d'idempotence de la requête qui a enregistré la réponse
+	RequestID string `bson:"request_id,omitempty" json:"-"`
+	// réponse envoyée par le joueur, ou réponses choisies d'une question à choix multiples
+	Answer  string   `bson:"answer,omitempty" json:"answer,omitempty"`
+	Answers []string `bson:"answers,omitempty" json:"answers,omitempty"`
+	// rareté de l'antisèche utilisée sur cette question
+	CheatSheet int `bson:"cheat_sheet,omitempty" json:"cheatSheet,omitempty"`
+	// détail des points gagnés pour cette réponse
+	Points *PointsBreakdown `bson:"points,omitempty" json:"points,omitempty"`
+}
+
+// PointsBreakdown détaille les points d'une réponse
+type PointsBreakdown struct {
+	Base        int `bson:"base" json:"base"`
+	SpeedBonus  int `bson:"speed_bonus" json:"speedBonus"`
+	StreakBonus int `bson:"streak_bonus" json:"streakBonus"`
+	Total       int `bson:"total" json:"total"`
+}
+
+// états d'un quiz
+const (
+	QuizInProgress = "in_progress"
+	QuizFinished   = "finished"
+	QuizAbandoned  = "abandoned"
+	QuizExpired    = "expired"
+)
+
+// modes de quiz : catégorie créée par un joueur ou Open Trivia DB
+const (
+	QuizModeCustom  = "custom"
+	QuizModeOpenTDB = "opentdb"
+)
+
+// règles de calcul des points
+const (
+	ScoringClassic     = "classic"
+	ScoringCompetitive = "competitive"
+)
+
+// PoolQuestion est une question Open Trivia DB conservée dans la réserve locale
+type PoolQuestion struct {
+	ID         string    `json:"id" bson:"_id,omitempty"`
+	Category   string    `json:"category" bson:"category"`
+	Difficulty string    `json:"difficulty" bson:"difficulty"`
+	Type       string    `json:"type" bson:"type"`
+	Question   Question  `json:"question" bson:"question"`
+	FetchedAt  time.Time `json:"fetchedAt" bson:"fetched_at"`
+}
+
+// difficultés et types de questions des quiz générés par Open Trivia DB
+const (
+	DifficultyAny    = "any"
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+
+	TypeMultiple = "multiple"
+	TypeBoolean  = "boolean"
+)
+
 type Question struct {
+	// type de question, choix unique si vide
+	Type            string   `bson:"type,omitempty" json:"type,omitempty"`
 	QuestionText    string   `bson:"question_text" json:"question_text"`
 	Responses       []string `bson:"responses" json:"responses"`
 	ResponseCorrect string   `bson:"response_correct" json:"response_correct"`
+	// bonnes réponses d'une question à choix multiples
+	ResponsesCorrect []string `bson:"responses_correct,omitempty" json:"responses_correct,omitempty"`
+	// autres réponses acceptées d'une question à réponse libre
+	Aliases []string `bson:"aliases,omitempty" json:"aliases,omitempty"`
+	// écart accepté : proportion de fautes de frappe pour une réponse libre (QUIZ_TEXT_TOLERANCE
+	// si absent, 0 pour une correspondance exacte), écart absolu avec la bonne valeur pour une
+	// réponse numérique (0 si absent)
+	Tolerance *float64 `bson:"tolerance,omitempty" json:"tolerance,omitempty"`
+	// explication et source (URL ou référence) affichées après la réponse
+	Explanation string `bson:"explanation,omitempty" json:"explanation,omitempty"`
+	Source      string `bson:"source,omitempty" json:"source,omitempty"`
 }
 
+// types de question
+const (
+	QuestionSingle   = "single"
+	QuestionBoolean  = "boolean"
+	QuestionMulti    = "multi"
+	QuestionFreeText = "text"
+	QuestionNumeric  = "numeric"
+)
+
+// Explanation est l'explication générée pour une question Open Trivia DB,
+// identifiée par l'empreinte de la question et de sa bonne réponse
+type Explanation struct {
+	Key       string    `json:"key" bson:"_id"`
+	Text      string    `json:"text" bson:"text"`
+	CreatedAt time.Time `json:"createdAt" bson:"created_at"`
+}
+
+// AuditEntry trace une action d'administration
+type AuditEntry struct {
+	ID        string                 `json:"id" bson:"_id,omitempty"`
+	Actor     string                 `json:"actor" bson:"actor"`
+	Action    string                 `json:"action" bson:"action"`
+	Target    string                 `json:"target" bson:"target"`
+	Details   map[string]interface{} `json:"details,omitempty" bson:"details,omitempty"`
+	CreatedAt time.Time              `json:"createdAt" bson:"created_at"`
+}
+
+// LedgerEntry est un mouvement de pièces et d'antisèches d'un utilisateur. Le journal n'est jamais
+// modifié, chaque entrée donne les soldes après le mouvement.
+type LedgerEntry struct {
+	ID     string `json:"ID" bson:"_id,omitempty"`
+	UserID string `json:"userId" bson:"user_id"`
+	// numéro du mouvement pour l'utilisateur, sans trou : 1, 2, 3...
+	Seq  int    `json:"seq" bson:"seq"`
+	Kind string `json:"kind" bson:"kind"`
+	// quiz, motif d'administration... à l'origine du mouvement
+	Reference  string         `json:"reference,omitempty" bson:"reference,omitempty"`
+	Coins      int            `json:"coins" bson:"coins"`
+	CoinsAfter int            `json:"coinsAfter" bson:"coins_after"`
+	Items      []ItemMovement `json:"items,omitempty" bson:"items,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt" bson:"created_at"`
+}
+
+// ItemMovement est la variation du nombre d'antisèches d'une rareté
+type ItemMovement struct {
+	Rarity        int `json:"rarity" bson:"rarity"`
+	Quantity      int `json:"quantity" bson:"quantity"`
+	QuantityAfter int `json:"quantityAfter" bson:"quantity_after"`
+}
+
+// types de mouvements du journal
+const (
+	LedgerQuizReward = "quiz_reward"
+	LedgerGachaPull  = "gacha_pull"
+	LedgerCheatSheet = "cheat_sheet"
+	LedgerAdminGrant = "admin_grant"
+)
+
 type ApiResponse struct {
 	Status  int         `json:"status"`
 	Message string      `json:"message"`
@@ -23,21 +23,18 @@ const categories = [
   "Cartoon & Animations",
 ];
 
-interface Category {
+// catégorie d'un joueur telle que listée, sans ses questions
+interface CategorySummary {
   Username: string;
   CategoryName: string;
-  Questions: {
-    question_text: string;
-    responses: string[];
-    response_correct: string;
-  }[];
+  QuestionCount: number;
 }
 
 const CategoriesPage = () => {
   const navigate = useNavigate();
   const { user, fetchFromBackend } = useAuth();
 
-  const [userCategories, setUserCategories] = useState<Category[]>([]);
+  const [userCategories, setUserCategories] = useState<CategorySummary[]>([]);
 
   useEffect(() => {
     const fetchUserCategories = async () => {
@@ -50,7 +47,7 @@ const CategoriesPage = () => {
 
           const data = await response.json();
           if (data && data.status === 200) {
-            setUserCategories(data.data as Category[]);
+            setUserCategories(data.data as CategorySummary[]);
           }
         } catch (error) {
           console.error(error);
@@ -67,10 +64,19 @@ const CategoriesPage = () => {
     });
   };
 
-  const handleModifyCategory = (category: Category) => {
-    navigate(`/quizmaster/create-category`, {
-      state: { selectedCategory: category },
-    });
+  // les questions et leurs réponses ne sont envoyées qu'au créateur de la catégorie
+  const handleModifyCategory = async (category: CategorySummary) => {
+    try {
+      const response = await fetchFromBackend(`/api/user/category/${encodeURIComponent(category.CategoryName)}`, "GET");
+      if (!response.ok) throw new Error("Erreur lors de la récupération de la catégorie.");
+
+      const data = await response.json();
+      navigate(`/quizmaster/create-category`, {
+        state: { selectedCategory: data.data },
+      });
+    } catch (error) {
+      console.error(error);
+    }
   }
 
   return (
@@ -20,7 +20,9 @@ const QuizGame = () => {
   const [quizID, setQuizID] = useState("");
   const [questionNumber, setQuestionNumber] = useState(1);
 
-  const [questions, setQuestions] = useState<Question[]>([]);
+  const [question, setQuestion] = useState<Question | null>(null);  // Question en cours
+  const [nextQuestion, setNextQuestion] = useState<Question | null>(null);  // Question suivante, reçue à la vérification
+  const [total, setTotal] = useState<number>(0);  // Nombre de questions
   const [choices, setChoices] = useState<string[]>([]);  // Choix des réponses
   const [correctAnswer, setCorrectAnswer] = useState<string>("");  // Réponse correcte
   const [selectedChoice, setSelectedChoice] = useState<string | null>(null);
@@ -52,13 +54,6 @@ const QuizGame = () => {
     }
   }, [quizType, method]);
 
-  useEffect(() => {
-    if (questions.length > 0 && questionNumber < questions.length) {
-      setChoices(questions[questionNumber].responses);
-      setSelectedChoice(null);
-    }
-  }, [questionNumber, questions]);
-
   const handleQuiz = async () => {
     let endpoint = `/api/quiz/${quizType}/${selectedCategory}`;
     if (method === "GET") {
@@ -82,9 +77,9 @@ const QuizGame = () => {
       }
       console.log("Données reçues:", data);
       setQuestionNumber(data.data.Number_question);
-      setQuestions(data.data.Questions);
-      setChoices(data.data.Questions[data.data.Number_question].responses);
-      setCorrectAnswer(data.data.Questions[data.data.Number_question].response_correct);
+      setTotal(data.data.Amount);
+      setQuestion(data.data.Question);
+      setChoices(data.data.Question?.responses || []);
       setQuizID(data.data.ID);
       setMark(data.data.Mark);
       setSelectedChoice(null);
@@ -105,14 +100,16 @@ const QuizGame = () => {
     const response = await fetchFromBackend("/api/quiz/verifyAnswer", "POST", JSON.stringify({
       quizID: quizID,
       answer: selectedChoice,
+      question: questionNumber,  // Refusée (409) si la question a déjà reçu une réponse
     }));
     const data = await response.json();
 
     if (response.ok) {
       if (data) {
         setIsAnswerChecked(true);
-        setCorrectAnswer(data.data);
-        console.log("Vraie reponse correcte", data.data);
+        setCorrectAnswer(data.data.correctAnswer);
+        setNextQuestion(data.data.next || null);
+        console.log("Vraie reponse correcte", data.data.correctAnswer);
       }
     } else {
       alert(data.message);
@@ -124,9 +121,10 @@ const QuizGame = () => {
       setMark(mark + 1);
     }
 
-    if (questionNumber < questions.length - 1) {
+    if (questionNumber < total - 1) {
       setQuestionNumber(questionNumber + 1);
-      setChoices(questions[questionNumber].responses);
+      setQuestion(nextQuestion);
+      setChoices(nextQuestion?.responses || []);
       setSelectedChoice(null);
       setIsAnswerChecked(false);
       setHasUsedCheatsheet(false);
@@ -144,7 +142,7 @@ const QuizGame = () => {
             ...user.Stats, 
             quizzes_played: user.Stats.quizzes_played + 1, 
             correct_responses: user.Stats.correct_responses + updatedMark,
-            full_marks: updatedMark === questions.length ? user.Stats.full_marks + 1 : user.Stats.full_marks,
+            full_marks: updatedMark === total ? user.Stats.full_marks + 1 : user.Stats.full_marks,
           } 
         });
       }
@@ -233,7 +231,7 @@ const QuizGame = () => {
           <div className="text-center">
             <h2 className="text-2xl font-bold mb-4 text-[#E470A3]">Résultat</h2>
             <p className="text-lg mb-6 text-white">
-              Vous avez {mark} / {questions.length} bonnes réponses !
+              Vous avez {mark} / {total} bonnes réponses !
             </p>
             <button
               className="py-3 px-6 text-lg font-semibold rounded-lg bg-[#E470A3] text-white hover:bg-[#9A60D1] transition duration-300"
@@ -254,7 +252,7 @@ const QuizGame = () => {
               className="text-lg mb-6 text-center max-w-2xl bg-[#4A3E7F] p-4 rounded-lg text-white"
               style={{ boxShadow: "0 0 10px rgba(228, 112, 163, 0.5)" }}
             >
-              {questions[questionNumber]?.question_text}
+              {question?.question_text}
             </p>
             <div className="grid grid-cols-2 gap-4 w-full">
               {choices.map((choice, index) => {